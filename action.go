@@ -0,0 +1,173 @@
+package rules
+
+import (
+	"bufio"
+	"fmt"
+	"strings"
+)
+
+// Action is executed by an Engine when the rule of a production holds.
+type Action interface {
+	execute(r *run) error
+}
+
+// ActionFunc is an Action implemented by a Go function, which can read and modify the working memory.
+type ActionFunc func(wm *WorkingMemory) error
+
+func (f ActionFunc) execute(r *run) error {
+	return f(r.wm)
+}
+
+// Halt returns an action that stops the engine after the current production has fired.
+func Halt() Action {
+	return halt{}
+}
+
+type halt struct{}
+
+func (halt) execute(r *run) error {
+	r.halted = true
+	return nil
+}
+
+// assignment is a declarative action setting an element to the value of an expression.
+type assignment struct {
+	name  string
+	value func(ctx RuleContext) (any, error)
+}
+
+func (a assignment) execute(r *run) error {
+	value, err := a.value(r.wm)
+	if err != nil {
+		return fmt.Errorf("SET %s: %w", a.name, err)
+	}
+	if err := r.wm.SetValue(a.name, value); err != nil {
+		return fmt.Errorf("SET %s: %w", a.name, err)
+	}
+	return nil
+}
+
+type actions []Action
+
+func (a actions) execute(r *run) error {
+	for _, action := range a {
+		if err := action.execute(r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ParseAction parses declarative actions, one per line or separated by semicolons
+// outside string literals:
+//
+//	SET upgradeClass = "business"
+//	SET eligible = passengerIsGoldCardHolder AND NOT blacklisted
+//	HALT
+//
+// The value of SET is any expression: a literal, TRUE or FALSE, the name of an
+// element, a function call or a boolean expression.
+func ParseAction(src string, opts ...ParseOption) (Action, error) {
+	var parsed actions
+
+	scanner := bufio.NewScanner(strings.NewReader(src))
+	line := 0
+	for scanner.Scan() {
+		line++
+		for _, stmt := range splitStatements(scanner.Text()) {
+			stmt = strings.TrimSpace(stmt)
+			if stmt == "" {
+				continue
+			}
+			action, err := parseStatement(stmt, opts)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", line, err)
+			}
+			parsed = append(parsed, action)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(parsed) == 0 {
+		return nil, fmt.Errorf("%w: no actions", ErrInvalidAction)
+	}
+	if len(parsed) == 1 {
+		return parsed[0], nil
+	}
+	return parsed, nil
+}
+
+// splitStatements splits line at the semicolons outside string literals.
+func splitStatements(line string) []string {
+	var stmts []string
+	start := 0
+	quoted, escaped := false, false
+	for i, c := range line {
+		switch {
+		case escaped:
+			escaped = false
+		case quoted && c == '\\':
+			escaped = true
+		case c == '"':
+			quoted = !quoted
+		case c == ';' && !quoted:
+			stmts = append(stmts, line[start:i])
+			start = i + 1
+		}
+	}
+	return append(stmts, line[start:])
+}
+
+func parseStatement(stmt string, opts []ParseOption) (Action, error) {
+	keyword, rest, _ := strings.Cut(stmt, " ")
+	switch strings.ToUpper(keyword) {
+	case "HALT":
+		if strings.TrimSpace(rest) != "" {
+			return nil, fmt.Errorf("%w: unexpected %q after HALT", ErrInvalidAction, rest)
+		}
+		return Halt(), nil
+	case "SET":
+		name, expr, ok := strings.Cut(rest, "=")
+		name = strings.TrimSpace(name)
+		if !ok || !isName(name) {
+			return nil, fmt.Errorf("%w: expected SET name = value, got %q", ErrInvalidAction, stmt)
+		}
+		value, err := parseValue(name, expr, opts)
+		if err != nil {
+			return nil, fmt.Errorf("%w: SET %s: %w", ErrInvalidAction, name, err)
+		}
+		return assignment{name: name, value: value}, nil
+	default:
+		return nil, fmt.Errorf("%w: unknown action %q", ErrInvalidAction, keyword)
+	}
+}
+
+// parseValue parses the value of an assignment: TRUE, FALSE or an expression.
+func parseValue(name, expr string, opts []ParseOption) (func(ctx RuleContext) (any, error), error) {
+	switch strings.TrimSpace(expr) {
+	case "TRUE":
+		return func(RuleContext) (any, error) { return true, nil }, nil
+	case "FALSE":
+		return func(RuleContext) (any, error) { return false, nil }, nil
+	}
+
+	r, err := Parse(name, expr, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return func(ctx RuleContext) (any, error) {
+		el, err := r.(*rule).evaluate(ctx)
+		if err != nil {
+			return nil, err
+		}
+		switch v := el.(type) {
+		case Attribute:
+			return v.getValue(), nil
+		case Variable:
+			return v.getValue(), nil
+		default:
+			return nil, fmt.Errorf("%w: %s has no value", ErrInvalidRule, el.getName())
+		}
+	}, nil
+}
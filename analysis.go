@@ -0,0 +1,122 @@
+package rules
+
+import (
+	"github.com/IAmRadek/rules/internal/sat"
+)
+
+// AnalysisOption configures AnalyzeRuleSet.
+type AnalysisOption func(*analysisConfig)
+
+type analysisConfig struct {
+	examples int
+}
+
+// WithMaxExamples sets how many examples of gaps AnalyzeRuleSet looks for. The
+// default is 3.
+func WithMaxExamples(n int) AnalysisOption {
+	return func(c *analysisConfig) {
+		c.examples = n
+	}
+}
+
+// Overlap is a pair of rules of a set that both hold for some contexts.
+type Overlap struct {
+	Rules [2]string
+	// Example is an assignment for which both rules hold.
+	Example Assignment
+}
+
+// RuleSetAnalysis holds the overlaps and gaps found in a rule set.
+type RuleSetAnalysis struct {
+	// Overlaps holds the pairs of rules that both hold for some contexts, in the
+	// evaluation order of the set.
+	Overlaps []Overlap
+	// Gaps holds examples of assignments for which no rule holds, each differing
+	// from the others in the value of at least one element.
+	Gaps []Assignment
+}
+
+// AnalyzeRuleSet looks for overlaps, pairs of rules of set that both hold for some
+// contexts, and gaps, contexts for which none of its rules hold. Both are mistakes
+// in sets of rules meant to be exclusive and exhaustive, such as fare bands.
+// Rules are analysed as described for FindSatisfyingAssignment, and they may
+// reference each other. Rules the set skips, because of their tags or validity
+// at the time of the analysis, are left out, and overrides are not taken into
+// account.
+func AnalyzeRuleSet(set RuleSet, opts ...AnalysisOption) (*RuleSetAnalysis, error) {
+	cfg := analysisConfig{examples: 3}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	rules := set.Rules()
+	var resolvers []ruleResolver
+	if rs, ok := set.(*ruleSet); ok {
+		now := rs.now()
+		active := rules[:0]
+		for _, r := range rules {
+			if rs.active(r, now) {
+				active = append(active, r)
+			}
+		}
+		rules = active
+	}
+	if resolver, ok := set.(ruleResolver); ok {
+		resolvers = append(resolvers, resolver)
+	}
+
+	s, lits, err := analyse(resolvers, rules...)
+	if err != nil {
+		return nil, err
+	}
+
+	analysis := &RuleSetAnalysis{}
+	for i := range rules {
+		for j := i + 1; j < len(rules); j++ {
+			if a, ok := s.solve(lits[i], lits[j]); ok {
+				analysis.Overlaps = append(analysis.Overlaps, Overlap{
+					Rules:   [2]string{rules[i].Name(), rules[j].Name()},
+					Example: a,
+				})
+			}
+		}
+	}
+
+	none := make([]sat.Literal, len(lits))
+	for i, l := range lits {
+		none[i] = l.Not()
+	}
+	for len(analysis.Gaps) < cfg.examples {
+		model, ok := s.solver.Solve(none...)
+		if !ok {
+			break
+		}
+		analysis.Gaps = append(analysis.Gaps, s.assignment(model))
+		s.exclude(model)
+	}
+	return analysis, nil
+}
+
+// exclude adds a clause excluding the values of the attributes and elements in
+// model from later solutions.
+func (s *skeleton) exclude(model []bool) {
+	var clause []sat.Literal
+	for _, l := range s.attributes {
+		if holds(model, l) {
+			clause = append(clause, l.Not())
+		} else {
+			clause = append(clause, l)
+		}
+	}
+	for _, d := range s.domains {
+		if !d.element || d.kind == kindUnknown {
+			continue
+		}
+		for _, l := range d.regions {
+			if holds(model, l) {
+				clause = append(clause, l.Not())
+			}
+		}
+	}
+	s.solver.AddClause(clause...)
+}
@@ -0,0 +1,75 @@
+package rules
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestAnalyzeRuleSet(t *testing.T) {
+	set := NewRuleSet(
+		MustParse("short", "distance LT 1000"),
+		MustParse("medium", "distance GTE 1000 AND distance LT 3000"),
+		MustParse("long", "distance GT 3000"),
+		MustParse("premium", "distance GTE 2500 AND NOT economy"),
+	)
+	analysis, err := AnalyzeRuleSet(set, WithMaxExamples(5))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var overlaps [][2]string
+	for _, o := range analysis.Overlaps {
+		overlaps = append(overlaps, o.Rules)
+		for _, name := range o.Rules {
+			r := set.(*ruleSet).rules[set.(*ruleSet).index[name]]
+			if got, err := r.Evaluate(o.Example.Context()); err != nil || !got {
+				t.Errorf("expected %s to hold for %v, got %v, %v", name, o.Example, got, err)
+			}
+		}
+	}
+	want := [][2]string{{"medium", "premium"}, {"long", "premium"}}
+	if !reflect.DeepEqual(overlaps, want) {
+		t.Errorf("expected overlaps %v, got %v", want, overlaps)
+	}
+
+	// Only a distance of 3000 is not covered, unless premium holds.
+	if len(analysis.Gaps) != 1 {
+		t.Fatalf("expected one gap, got %v", analysis.Gaps)
+	}
+	gap := analysis.Gaps[0]
+	if gap["distance"] != 3000.0 || gap["economy"] != true {
+		t.Errorf("expected a gap at distance 3000 in economy, got %v", gap)
+	}
+	ok, err := set.Evaluate(gap.Context())
+	if err != nil || ok {
+		t.Errorf("expected no rule to hold for %v, got %v, %v", gap, ok, err)
+	}
+}
+
+func TestAnalyzeRuleSetReferencesAndValidity(t *testing.T) {
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	set, err := NewRuleSetWithOptions([]Rule{
+		MustParse("adult", "age GTE 18"),
+		MustParse("minor", "NOT adult"),
+		MustParse("old", "age GTE 60", WithValidity(time.Time{}, now.AddDate(-1, 0, 0))),
+	}, WithClock(func() time.Time { return now }))
+	if err != nil {
+		t.Fatal(err)
+	}
+	analysis, err := AnalyzeRuleSet(set)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(analysis.Overlaps) != 0 || len(analysis.Gaps) != 0 {
+		t.Errorf("expected no overlaps and no gaps, got %v", analysis)
+	}
+
+	analysis, err = AnalyzeRuleSet(NewRuleSet(MustParse("a", "gold"), MustParse("b", "vip")), WithMaxExamples(10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(analysis.Overlaps) != 1 || len(analysis.Gaps) != 1 {
+		t.Errorf("expected an overlap and a gap, got %v", analysis)
+	}
+}
@@ -0,0 +1,104 @@
+package rules
+
+import (
+	"errors"
+	"fmt"
+)
+
+// CheckRule verifies, without evaluating it, that rule can be evaluated against
+// contexts matching schema: every name is declared in the schema or refers to a
+// rule, operands of NOT, AND, OR and XOR are attributes, operands of comparisons
+// are variables of compatible types, arguments of calls match the parameters of the
+// functions, and the rule results in an attribute. Every problem found is reported.
+func CheckRule(r Rule, schema *Schema) error {
+	return errors.Join(checkRule(r, schema, nil)...)
+}
+
+// checkRule checks r and returns the problems found, resolving the names missing
+// from schema as rules of the resolvers, in addition to the rules r references.
+func checkRule(r Rule, schema *Schema, resolvers []ruleResolver) []error {
+	rr, ok := r.(*rule)
+	if !ok {
+		return []error{fmt.Errorf("%w: rule %s cannot be checked", ErrInvalidRule, r.Name())}
+	}
+	if rr.references != nil {
+		resolvers = append(resolvers, rr.references)
+	}
+
+	c := &checker{rule: rr, schema: schema, resolvers: resolvers}
+	root := rr.tree()
+	if k := c.check(root); k != kindBool && k != kindUnknown {
+		c.errorf("%w: %s results in a %s, not an attribute", ErrTypeMismatch, root, k)
+	}
+	return c.problems
+}
+
+type checker struct {
+	rule      *rule
+	schema    *Schema
+	resolvers []ruleResolver
+	problems  []error
+}
+
+func (c *checker) errorf(format string, args ...any) {
+	c.problems = append(c.problems, fmt.Errorf("rule %s: %w", c.rule.name, fmt.Errorf(format, args...)))
+}
+
+// check checks n and returns the kind of its value, or kindUnknown if it cannot
+// be told because of a problem already reported.
+func (c *checker) check(n *node) valueKind {
+	kinds := make([]valueKind, len(n.children))
+	for i, child := range n.children {
+		kinds[i] = c.check(child)
+	}
+
+	if name, _, ok := parseCall(n.token); ok {
+		fun, ok := c.rule.functions.lookup(name)
+		if !ok {
+			c.errorf("%w: %s", ErrUnknownFunction, name)
+			return kindUnknown
+		}
+		for i, k := range kinds {
+			if !fun.accepts(i, k) {
+				c.errorf("%w: argument %s of %s must be a %s, not a %s", ErrTypeMismatch, n.children[i], name, kindOf(fun.params[i]), k)
+			}
+		}
+		return kindOf(fun.result)
+	}
+
+	switch {
+	case n.token == kNOT || n.isLogical():
+		for i, k := range kinds {
+			if k != kindBool && k != kindUnknown {
+				c.errorf("%w: operand %s of %s must be an attribute, not a %s", ErrTypeMismatch, n.children[i], n.token, k)
+			}
+		}
+		return kindBool
+	case isComparison(n.token):
+		for i, k := range kinds {
+			if k == kindBool {
+				c.errorf("%w: operand %s of %s must be a variable, not an attribute", ErrTypeMismatch, n.children[i], n.token)
+				return kindBool
+			}
+		}
+		if kinds[0] != kindUnknown && kinds[1] != kindUnknown && kinds[0] != kinds[1] {
+			c.errorf("%w: cannot compare %s, a %s, with %s, a %s", ErrTypeMismatch, n.children[0], kinds[0], n.children[1], kinds[1])
+		}
+		return kindBool
+	case isStringLiteral(n.token):
+		return kindString
+	case isNumberLiteral(n.token):
+		return kindNumber
+	}
+
+	if t, ok := c.schema.typeOf(n.token); ok {
+		return kindOf(t)
+	}
+	for _, resolver := range c.resolvers {
+		if _, ok := resolver.lookupRule(n.token); ok {
+			return kindBool
+		}
+	}
+	c.errorf("%w: %s", ErrUnknownElement, n.token)
+	return kindUnknown
+}
@@ -0,0 +1,93 @@
+package rules
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestCheckRule(t *testing.T) {
+	schema, err := NewSchema(
+		NewAttribute("gold"),
+		NewAttribute("vip"),
+		NewVariable[int]("age"),
+		NewVariable[float64]("miles"),
+		NewVariable[string]("country"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	registry, err := NewRuleRegistry(MustParse("adult", "age GTE 18"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		expr     string
+		err      error
+		problems []string
+	}{
+		{expr: "gold AND age GT 18 OR country EQ \"pl\""},
+		{expr: "age GT miles AND NOT vip"},
+		{expr: "LEN(country) GT 2 AND LOWER(country) EQ \"pl\""},
+		{expr: "adult AND gold"},
+		{expr: "gold GT vip", err: ErrTypeMismatch, problems: []string{"operand gold of GT must be a variable"}},
+		{expr: "age AND gold", err: ErrTypeMismatch, problems: []string{"operand age of AND must be an attribute, not a number"}},
+		{expr: "NOT country", err: ErrTypeMismatch, problems: []string{"operand country of NOT must be an attribute, not a string"}},
+		{expr: "age EQ \"18\"", err: ErrTypeMismatch, problems: []string{"cannot compare age, a number, with \"18\", a string"}},
+		{expr: "LEN(age) GT 1", err: ErrTypeMismatch, problems: []string{"argument age of LEN must be a string, not a number"}},
+		{expr: "LEN(country)", err: ErrTypeMismatch, problems: []string{"LEN(country) results in a number, not an attribute"}},
+		{
+			expr:     "unknown AND (age AND country GT 1)",
+			err:      ErrUnknownElement,
+			problems: []string{"unknown element: unknown", "operand age of AND", "cannot compare country, a string, with 1, a number"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			err := CheckRule(MustParse("test", tt.expr, WithRules(registry)), schema)
+			if !errors.Is(err, tt.err) || (tt.err == nil) != (err == nil) {
+				t.Fatalf("expected %v, got %v", tt.err, err)
+			}
+			for _, want := range tt.problems {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("expected %q in %v", want, err)
+				}
+			}
+		})
+	}
+
+	if err := CheckRule(constantRule(true), schema); !errors.Is(err, ErrInvalidRule) {
+		t.Errorf("expected ErrInvalidRule, got %v", err)
+	}
+}
+
+func TestLoadRulesChecksTypes(t *testing.T) {
+	src := `
+elements:
+  - name: age
+    type: int
+  - name: gold
+    type: attribute
+rules:
+  - name: adult
+    expr: age GTE 18
+  - name: upgrade
+    expr: adult AND gold GT 1
+overrides:
+  - rule: adult
+    when: age
+`
+	_, err := LoadRules(strings.NewReader(src), "rules.yaml")
+	if !errors.Is(err, ErrTypeMismatch) {
+		t.Fatalf("expected ErrTypeMismatch, got %v", err)
+	}
+	for _, want := range []string{
+		"rules.yaml:11: rule upgrade: type mismatch: operand gold of GT",
+		"rules.yaml:14: rule adult.when: type mismatch: age results in a number",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected %q in %v", want, err)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+// Command rulelint reports likely mistakes in rule expressions, such as
+// contradictions, double negations and mixes of AND and OR without parentheses.
+//
+// Usage:
+//
+//	rulelint [-min severity] file...
+//	rulelint [-min severity] -e expression
+//
+// Files are rule files in YAML or JSON, as read by rules.LoadRulesFile. Each
+// diagnostic is printed on its own line, with the rule it was found in and its
+// position in the expression of the rule. The exit status is 1 if a warning or
+// an error was reported, and 2 if a file could not be loaded.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/IAmRadek/rules"
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("rulelint", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	expr := flags.String("e", "", "lint the given `expression` instead of files")
+	minimum := flags.String("min", "info", "report diagnostics of at least the given `severity`: info, warning or error")
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "usage: rulelint [-min severity] file...")
+		fmt.Fprintln(stderr, "       rulelint [-min severity] -e expression")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	threshold, ok := severities[*minimum]
+	if !ok {
+		fmt.Fprintf(stderr, "rulelint: unknown severity %q\n", *minimum)
+		return 2
+	}
+	if (*expr == "") == (flags.NArg() == 0) {
+		flags.Usage()
+		return 2
+	}
+
+	l := &linter{out: stdout, min: threshold}
+	if *expr != "" {
+		r, err := rules.Parse("expression", *expr)
+		if err != nil {
+			fmt.Fprintf(stderr, "rulelint: %v\n", err)
+			return 2
+		}
+		l.lint("", r)
+		return l.status()
+	}
+
+	status := 0
+	for _, path := range flags.Args() {
+		file, err := rules.LoadRulesFile(path)
+		if err != nil {
+			fmt.Fprintf(stderr, "rulelint: %v\n", err)
+			status = 2
+			continue
+		}
+		for _, r := range file.RuleSet.Rules() {
+			l.lint(path+": ", r)
+		}
+	}
+	if status == 0 {
+		status = l.status()
+	}
+	return status
+}
+
+var severities = map[string]rules.Severity{
+	"info":    rules.SeverityInfo,
+	"warning": rules.SeverityWarning,
+	"error":   rules.SeverityError,
+}
+
+type linter struct {
+	out io.Writer
+	min rules.Severity
+	// failed is true once a warning or an error is reported.
+	failed bool
+}
+
+func (l *linter) lint(prefix string, r rules.Rule) {
+	for _, d := range rules.Lint(r) {
+		if d.Severity < l.min {
+			continue
+		}
+		fmt.Fprintf(l.out, "%s%s:%s\n", prefix, r.Name(), d)
+		if d.Severity >= rules.SeverityWarning {
+			l.failed = true
+		}
+	}
+}
+
+func (l *linter) status() int {
+	if l.failed {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestRun(t *testing.T) {
+	tests := []struct {
+		args   []string
+		status int
+		out    string
+	}{
+		{args: []string{"-e", "gold AND vip"}, status: 0},
+		{args: []string{"-e", "(gold) AND vip"}, status: 0, out: "expression:1:1: info: parentheses around gold are redundant"},
+		{args: []string{"-min", "warning", "-e", "(gold) AND vip"}, status: 0},
+		{args: []string{"-e", "gold OR vip AND NOT NOT vip"}, status: 1, out: "expression:1:13: warning"},
+		{args: []string{"../../testdata/rules/upgrade.yaml"}, status: 0},
+		{args: []string{"../../testdata/rules/broken.yaml"}, status: 2},
+		{args: []string{"-e", "gold AND"}, status: 2},
+		{args: []string{"-min", "fatal", "-e", "gold"}, status: 2},
+		{args: nil, status: 2},
+	}
+	for _, tt := range tests {
+		t.Run(strings.Join(tt.args, " "), func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			if status := run(tt.args, &stdout, &stderr); status != tt.status {
+				t.Errorf("expected status %d, got %d: %s%s", tt.status, status, &stdout, &stderr)
+			}
+			if !strings.Contains(stdout.String(), tt.out) {
+				t.Errorf("expected %q in %q", tt.out, &stdout)
+			}
+		})
+	}
+}
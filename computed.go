@@ -0,0 +1,125 @@
+package rules
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// computedElement is a rule element whose value is derived from other elements
+// of the context at evaluation time.
+type computedElement interface {
+	RuleElement
+
+	compute(ctx RuleContext) (RuleElement, error)
+	// valueType returns the type of the computed value, which is bool for attributes.
+	valueType() reflect.Type
+}
+
+type computedAttribute struct {
+	name string
+	fn   func(ctx RuleContext) (bool, error)
+}
+
+// NewComputedAttribute creates an attribute whose value is computed by fn when a rule
+// referencing it is evaluated. The value is computed at most once per evaluation.
+func NewComputedAttribute(name string, fn func(ctx RuleContext) (bool, error)) RuleElement {
+	return computedAttribute{
+		name: name,
+		fn:   fn,
+	}
+}
+
+func (c computedAttribute) String() string {
+	return fmt.Sprintf("%s(computed)", c.name)
+}
+
+func (c computedAttribute) getType() string {
+	return "attribute"
+}
+
+func (c computedAttribute) getName() string {
+	return c.name
+}
+
+func (c computedAttribute) valueType() reflect.Type {
+	return reflect.TypeOf(false)
+}
+
+func (c computedAttribute) compute(ctx RuleContext) (RuleElement, error) {
+	value, err := c.fn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return attribute{name: c.name, value: value}, nil
+}
+
+type computedVariable[T ordered] struct {
+	name string
+	fn   func(ctx RuleContext) (T, error)
+}
+
+// NewComputedVariable creates a variable whose value is computed by fn when a rule
+// referencing it is evaluated. The value is computed at most once per evaluation.
+func NewComputedVariable[T ordered](name string, fn func(ctx RuleContext) (T, error)) RuleElement {
+	return computedVariable[T]{
+		name: name,
+		fn:   fn,
+	}
+}
+
+func (c computedVariable[T]) String() string {
+	return fmt.Sprintf("%s(computed)", c.name)
+}
+
+func (c computedVariable[T]) getType() string {
+	return "variable"
+}
+
+func (c computedVariable[T]) getName() string {
+	return c.name
+}
+
+func (c computedVariable[T]) valueType() reflect.Type {
+	return reflect.TypeOf((*T)(nil)).Elem()
+}
+
+func (c computedVariable[T]) compute(ctx RuleContext) (RuleElement, error) {
+	value, err := c.fn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return NewVariable[T](c.name)(value), nil
+}
+
+// AttributeValue returns the value of the attribute with the given name.
+// Computed attributes are evaluated, which makes it usable inside computed elements.
+func AttributeValue(ctx RuleContext, name string) (bool, error) {
+	el, err := newEvaluation(ctx).resolve(name)
+	if err != nil {
+		return false, err
+	}
+	a, ok := el.(Attribute)
+	if !ok {
+		return false, fmt.Errorf("%w: %s is not an attribute", ErrInvalidRule, name)
+	}
+	return a.getValue(), nil
+}
+
+// VariableValue returns the value of the variable with the given name.
+// Computed variables are evaluated, which makes it usable inside computed elements.
+func VariableValue[T ordered](ctx RuleContext, name string) (T, error) {
+	var zero T
+	el, err := newEvaluation(ctx).resolve(name)
+	if err != nil {
+		return zero, err
+	}
+	v, ok := el.(Variable)
+	if !ok {
+		return zero, fmt.Errorf("%w: %s is not a variable", ErrInvalidRule, name)
+	}
+	value, ok := v.getValue().(T)
+	if !ok {
+		return zero, fmt.Errorf("%w: %s holds %T, not %T", ErrInvalidRule, name, v.getValue(), zero)
+	}
+	return value, nil
+}
@@ -0,0 +1,81 @@
+package rules
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestComputedAttribute(t *testing.T) {
+	var gold = NewAttribute("gold")
+	var miles = NewVariable[int]("miles")
+
+	calls := 0
+	frequentFlyer := NewComputedAttribute("frequentFlyer", func(ctx RuleContext) (bool, error) {
+		calls++
+		isGold, err := AttributeValue(ctx, "gold")
+		if err != nil {
+			return false, err
+		}
+		m, err := VariableValue[int](ctx, "miles")
+		if err != nil {
+			return false, err
+		}
+		return isGold || m > 10000, nil
+	})
+
+	r := MustParse("rule", "frequentFlyer AND (frequentFlyer OR gold)")
+
+	tests := []struct {
+		ctx  RuleContext
+		want bool
+	}{
+		{ctx: NewContext(gold(true), miles(0), frequentFlyer), want: true},
+		{ctx: NewContext(gold(false), miles(20000), frequentFlyer), want: true},
+		{ctx: NewContext(gold(false), miles(10), frequentFlyer), want: false},
+	}
+	for _, tt := range tests {
+		calls = 0
+		got, err := r.Evaluate(tt.ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("Evaluate(%v) = %v, want %v", tt.ctx, got, tt.want)
+		}
+		if calls != 1 {
+			t.Errorf("computed attribute evaluated %d times, want 1", calls)
+		}
+	}
+}
+
+func TestComputedVariable(t *testing.T) {
+	var weight = NewVariable[float64]("weight")
+	var limit = NewVariable[float64]("limit")
+	total := NewComputedVariable[float64]("total", func(ctx RuleContext) (float64, error) {
+		w, err := VariableValue[float64](ctx, "weight")
+		return w * 2, err
+	})
+
+	r := MustParse("rule", "total LTE limit")
+	got, err := r.Evaluate(NewContext(weight(3), limit(7), total))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got {
+		t.Errorf("Evaluate() = %v, want true", got)
+	}
+}
+
+func TestComputedCycle(t *testing.T) {
+	a := NewComputedAttribute("a", func(ctx RuleContext) (bool, error) {
+		return AttributeValue(ctx, "b")
+	})
+	b := NewComputedAttribute("b", func(ctx RuleContext) (bool, error) {
+		return MustParse("b", "NOT a").Evaluate(ctx)
+	})
+
+	_, err := MustParse("rule", "a").Evaluate(NewContext(a, b))
+	if !errors.Is(err, ErrCyclicDependency) {
+		t.Errorf("Evaluate() error = %v, want %v", err, ErrCyclicDependency)
+	}
+}
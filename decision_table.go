@@ -0,0 +1,670 @@
+package rules
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// maxValidationSamples limits the number of input combinations Validate evaluates.
+const maxValidationSamples = 100000
+
+// HitPolicy decides which rows of a decision table make up its decision when more
+// than one row matches. The policies follow DMN.
+type HitPolicy int
+
+const (
+	// HitUnique requires at most one row to match. Matching more is an ErrHitPolicy.
+	HitUnique HitPolicy = iota
+	// HitFirst uses the first matching row.
+	HitFirst
+	// HitPriority uses the matching row with the highest priority, or the first of them on a tie.
+	HitPriority
+	// HitCollect uses every matching row, in table order.
+	HitCollect
+	// HitCollectSum sums the outputs of the matching rows.
+	HitCollectSum
+	// HitCollectMin uses the smallest output of the matching rows.
+	HitCollectMin
+	// HitCollectMax uses the largest output of the matching rows.
+	HitCollectMax
+	// HitCollectCount counts the matching rows in every output, which is 0 when none match.
+	HitCollectCount
+)
+
+var hitPolicies = [...]string{"U", "F", "P", "C", "C+", "C<", "C>", "C#"}
+
+// String returns the DMN abbreviation of the policy, such as "F" or "C+".
+func (p HitPolicy) String() string {
+	if p < 0 || int(p) >= len(hitPolicies) {
+		return "HitPolicy(" + strconv.Itoa(int(p)) + ")"
+	}
+	return hitPolicies[p]
+}
+
+// DecisionRow is a row of a decision table.
+type DecisionRow struct {
+	// Conditions holds a unary test for each input of the table:
+	//
+	//	-            any value
+	//	GTE 18       a comparison of the input with an expression
+	//	"pl"         equality with an expression
+	//	"pl", "de"   any of the tests
+	//	[1..5)       a range, including the bounds in square brackets
+	//	TRUE, FALSE  the value of an attribute
+	Conditions []string
+	// Outputs holds a value for each output of the table. A nil value leaves the output unset.
+	Outputs []any
+	// Priority orders the matching rows under HitPriority.
+	Priority int
+}
+
+// DecisionTable maps inputs to outputs with rows of conditions. Each condition is
+// a unary test of a single input, parsed as a rule.
+type DecisionTable struct {
+	name    string
+	inputs  []string
+	outputs []string
+	policy  HitPolicy
+	opts    []ParseOption
+	rows    []decisionRow
+}
+
+// decisionRow is a row with its parsed conditions. Conditions matching any value are nil.
+type decisionRow struct {
+	DecisionRow
+	cells []Rule
+	// bools marks the inputs the row tests as attributes.
+	bools []bool
+}
+
+// DecisionTableOption configures a DecisionTable.
+type DecisionTableOption func(*DecisionTable)
+
+// WithHitPolicy sets the hit policy of the table. The default is HitUnique.
+func WithHitPolicy(policy HitPolicy) DecisionTableOption {
+	return func(t *DecisionTable) {
+		t.policy = policy
+	}
+}
+
+// WithCellOptions sets the options used to parse the conditions of the table.
+func WithCellOptions(opts ...ParseOption) DecisionTableOption {
+	return func(t *DecisionTable) {
+		t.opts = append(t.opts, opts...)
+	}
+}
+
+// NewDecisionTable creates a decision table without rows, with the given input
+// and output names.
+func NewDecisionTable(name string, inputs, outputs []string, opts ...DecisionTableOption) (*DecisionTable, error) {
+	if len(outputs) == 0 {
+		return nil, fmt.Errorf("%w: %s has no outputs", ErrInvalidTable, name)
+	}
+	seen := make(map[string]bool)
+	for _, column := range append(append([]string{}, inputs...), outputs...) {
+		if !isName(column) {
+			return nil, fmt.Errorf("%w: invalid column name %q", ErrInvalidTable, column)
+		}
+		if seen[column] {
+			return nil, fmt.Errorf("%w: duplicate column %s", ErrInvalidTable, column)
+		}
+		seen[column] = true
+	}
+
+	t := &DecisionTable{name: name, inputs: inputs, outputs: outputs}
+	for _, opt := range opts {
+		opt(t)
+	}
+	return t, nil
+}
+
+// Name returns the name of the table.
+func (t *DecisionTable) Name() string {
+	return t.name
+}
+
+// AddRow parses the conditions of row and adds it to the table. Rows with the same
+// conditions as an existing row are rejected.
+func (t *DecisionTable) AddRow(row DecisionRow) error {
+	n := len(t.rows) + 1
+	if len(row.Conditions) != len(t.inputs) {
+		return fmt.Errorf("%w: row %d has %d conditions, expected %d", ErrInvalidTable, n, len(row.Conditions), len(t.inputs))
+	}
+	if len(row.Outputs) != len(t.outputs) {
+		return fmt.Errorf("%w: row %d has %d outputs, expected %d", ErrInvalidTable, n, len(row.Outputs), len(t.outputs))
+	}
+
+	parsed := decisionRow{
+		DecisionRow: row,
+		cells:       make([]Rule, len(t.inputs)),
+		bools:       make([]bool, len(t.inputs)),
+	}
+	for i, input := range t.inputs {
+		expr, bools, err := cellExpression(input, row.Conditions[i])
+		if err != nil {
+			return fmt.Errorf("row %d, %s: %w", n, input, err)
+		}
+		if expr == "" {
+			continue
+		}
+		cell, err := Parse(fmt.Sprintf("%s.row%d.%s", t.name, n, input), expr, t.opts...)
+		if err != nil {
+			return fmt.Errorf("row %d, %s: %w", n, input, err)
+		}
+		parsed.cells[i] = cell
+		parsed.bools[i] = bools
+	}
+
+	for i, other := range t.rows {
+		if sameConditions(parsed.cells, other.cells) {
+			return fmt.Errorf("%w: row %d has the same conditions as row %d", ErrInvalidTable, n, i+1)
+		}
+	}
+
+	t.rows = append(t.rows, parsed)
+	return nil
+}
+
+// cellExpression converts the unary test of a cell into an expression of input.
+// It also reports whether the cell tests input as an attribute.
+func cellExpression(input, cell string) (string, bool, error) {
+	cell = strings.TrimSpace(cell)
+	if cell == "" || cell == "-" {
+		return "", false, nil
+	}
+	if expr, ok := rangeExpression(input, cell); ok {
+		return expr, false, nil
+	}
+
+	tokens, _, err := scan(cell)
+	if err != nil {
+		return "", false, err
+	}
+
+	var tests []string
+	bools := false
+	start, depth := 0, 0
+	for i := 0; i <= len(tokens); i++ {
+		if i < len(tokens) {
+			switch tokens[i].text {
+			case "(":
+				depth++
+				continue
+			case ")":
+				depth--
+				continue
+			case ",":
+				if depth > 0 {
+					continue
+				}
+			default:
+				continue
+			}
+		}
+
+		end := len(cell)
+		if i < len(tokens) {
+			end = tokens[i].pos
+		}
+		test := strings.TrimSpace(cell[start:end])
+		if i < len(tokens) {
+			start = tokens[i].pos + 1
+		}
+
+		first, _, _ := strings.Cut(test, " ")
+		switch {
+		case test == "":
+			return "", false, fmt.Errorf("%w: empty test in %q", ErrInvalidTable, cell)
+		case test == "TRUE":
+			tests = append(tests, input)
+			bools = true
+		case test == "FALSE":
+			tests = append(tests, kNOT+" "+input)
+			bools = true
+		case isComparison(first):
+			tests = append(tests, input+" "+test)
+		default:
+			tests = append(tests, input+" "+kEQ+" ("+test+")")
+		}
+	}
+
+	if len(tests) == 1 {
+		return tests[0], bools, nil
+	}
+	return "(" + strings.Join(tests, ") "+kOR+" (") + ")", bools, nil
+}
+
+// rangeExpression converts a range such as [1..5] into an expression of input.
+// Square brackets include the bound, parentheses exclude it.
+func rangeExpression(input, cell string) (string, bool) {
+	if len(cell) < 2 || !strings.ContainsRune("[(", rune(cell[0])) || !strings.ContainsRune("])", rune(cell[len(cell)-1])) {
+		return "", false
+	}
+	low, high, ok := strings.Cut(cell[1:len(cell)-1], "..")
+	if !ok {
+		return "", false
+	}
+
+	lower, upper := kGTE, kLTE
+	if cell[0] == '(' {
+		lower = kGT
+	}
+	if cell[len(cell)-1] == ')' {
+		upper = kLT
+	}
+	return fmt.Sprintf("(%s %s %s) %s (%s %s %s)",
+		input, lower, strings.TrimSpace(low), kAND, input, upper, strings.TrimSpace(high)), true
+}
+
+func sameConditions(a, b []Rule) bool {
+	for i := range a {
+		if (a[i] == nil) != (b[i] == nil) {
+			return false
+		}
+		if a[i] != nil && fmt.Sprint(a[i]) != fmt.Sprint(b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// Evaluate evaluates the table against ctx and combines the matching rows according
+// to its hit policy.
+func (t *DecisionTable) Evaluate(ctx RuleContext) (*Decision, error) {
+	ev := newEvaluation(ctx)
+
+	var matched []int
+	for i := range t.rows {
+		ok, err := t.rows[i].matches(ev)
+		if err != nil {
+			return nil, fmt.Errorf("%s: row %d: %w", t.name, i+1, err)
+		}
+		if !ok {
+			continue
+		}
+		matched = append(matched, i)
+		if t.policy == HitFirst {
+			break
+		}
+	}
+
+	switch t.policy {
+	case HitUnique:
+		if len(matched) > 1 {
+			return nil, fmt.Errorf("%w: %s: rows %s match", ErrHitPolicy, t.name, rowList(matched))
+		}
+	case HitPriority:
+		if len(matched) > 1 {
+			matched = t.winners(matched)
+		}
+	}
+
+	d := &Decision{Rows: matched, values: make(map[string][]any)}
+	for j, output := range t.outputs {
+		for _, i := range matched {
+			if v := t.rows[i].Outputs[j]; v != nil {
+				d.values[output] = append(d.values[output], v)
+			}
+		}
+	}
+	if t.policy == HitCollectCount {
+		// Every matching row counts, whether or not it sets the output.
+		for _, output := range t.outputs {
+			d.values[output] = []any{len(matched)}
+		}
+		return d, nil
+	}
+	if len(matched) == 0 {
+		return d, nil
+	}
+
+	for _, output := range t.outputs {
+		values := d.values[output]
+		var err error
+		switch t.policy {
+		case HitCollectSum:
+			values, err = sum(values)
+		case HitCollectMin:
+			values, err = extreme(values, -1)
+		case HitCollectMax:
+			values, err = extreme(values, 1)
+		default:
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s: %w", t.name, output, err)
+		}
+		d.values[output] = values
+	}
+
+	return d, nil
+}
+
+func (r *decisionRow) matches(ctx RuleContext) (bool, error) {
+	for _, cell := range r.cells {
+		if cell == nil {
+			continue
+		}
+		ok, err := cell.Evaluate(ctx)
+		if err != nil || !ok {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+func rowList(rows []int) string {
+	s := make([]string, len(rows))
+	for i, row := range rows {
+		s[i] = strconv.Itoa(row + 1)
+	}
+	return strings.Join(s, ", ")
+}
+
+func sum(values []any) ([]any, error) {
+	if len(values) == 0 {
+		return nil, nil
+	}
+	var ints int64
+	var floats float64
+	integral := true
+	for _, v := range values {
+		rv := reflect.ValueOf(v)
+		switch {
+		case isInt(rv.Kind()):
+			ints += rv.Int()
+		case isUint(rv.Kind()):
+			ints += int64(rv.Uint())
+		case isNumber(rv.Kind()):
+			floats += rv.Float()
+			integral = false
+		default:
+			return nil, fmt.Errorf("%w: cannot sum %T", ErrInvalidTable, v)
+		}
+	}
+	if integral {
+		return []any{ints}, nil
+	}
+	return []any{floats + float64(ints)}, nil
+}
+
+// extreme returns the smallest value for sign -1 and the largest for sign 1.
+func extreme(values []any, sign int) ([]any, error) {
+	if len(values) == 0 {
+		return nil, nil
+	}
+	best := values[0]
+	for _, v := range values[1:] {
+		c, ok := compareValues(v, best)
+		if !ok {
+			return nil, fmt.Errorf("%w: cannot compare %T with %T", ErrInvalidTable, v, best)
+		}
+		if c == sign {
+			best = v
+		}
+	}
+	return []any{best}, nil
+}
+
+// Decision is the outcome of evaluating a decision table.
+type Decision struct {
+	// Rows holds the indexes of the rows that make up the decision, in table order.
+	Rows   []int
+	values map[string][]any
+}
+
+// Matched reports whether any row matched.
+func (d *Decision) Matched() bool {
+	return len(d.Rows) > 0
+}
+
+// Value returns the value of the named output. Under HitCollect, it is the value
+// of the first matching row.
+func (d *Decision) Value(name string) (any, bool) {
+	values := d.values[name]
+	if len(values) == 0 {
+		return nil, false
+	}
+	return values[0], true
+}
+
+// Values returns every value of the named output, which holds more than one only
+// under HitCollect.
+func (d *Decision) Values(name string) []any {
+	return d.values[name]
+}
+
+// Output returns the value of the named output of d as a T. Values are converted to
+// named types of the same kind, and numbers to the requested numeric type when no
+// precision is lost.
+func Output[T any](d *Decision, name string) (T, error) {
+	var zero T
+	v, ok := d.Value(name)
+	if !ok {
+		if !d.Matched() {
+			return zero, fmt.Errorf("%w: %s", ErrNoMatch, name)
+		}
+		return zero, fmt.Errorf("%w: no value for output %s", ErrMissingDataInContext, name)
+	}
+	if out, ok := v.(T); ok {
+		return out, nil
+	}
+
+	if typ := reflect.TypeOf(zero); typ != nil {
+		if converted, ok := convertValue(v, typ); ok {
+			return converted.Interface().(T), nil
+		}
+	}
+	return zero, fmt.Errorf("%w: output %s holds %T, not %T", ErrInvalidTable, name, v, zero)
+}
+
+// Validate looks for combinations of inputs that no row matches, rows that overlap
+// under HitUnique, and rows that never make up the decision. Inputs are sampled
+// around the literals the conditions compare them with, so conditions comparing
+// inputs with other elements are not taken into account.
+func (t *DecisionTable) Validate() error {
+	samples := make([][]any, len(t.inputs))
+	total := 1
+	for i := range t.inputs {
+		samples[i] = t.samples(i)
+		if len(samples[i]) > 0 {
+			total *= len(samples[i])
+		}
+		if total > maxValidationSamples {
+			return fmt.Errorf("%w: %s has too many combinations of inputs to validate", ErrInvalidTable, t.name)
+		}
+	}
+
+	var problems []error
+	overlaps := make(map[[2]int]bool)
+	used := make([]bool, len(t.rows))
+	gaps, example := 0, ""
+
+	for k := 0; k < total; k++ {
+		var elems []RuleElement
+		rest := k
+		for i, values := range samples {
+			if len(values) == 0 {
+				continue
+			}
+			elem, _ := newElement(t.inputs[i], values[rest%len(values)])
+			elems = append(elems, elem)
+			rest /= len(values)
+		}
+		ctx := NewContext(elems...)
+
+		var matched []int
+		failed := false
+		for i := range t.rows {
+			ok, err := t.rows[i].matches(ctx)
+			if err != nil {
+				failed = true
+				break
+			}
+			if ok {
+				matched = append(matched, i)
+			}
+		}
+		if failed {
+			continue
+		}
+
+		switch {
+		case len(matched) == 0:
+			if gaps == 0 {
+				example = describeSample(elems)
+			}
+			gaps++
+		case t.policy == HitUnique:
+			for a := 0; a < len(matched); a++ {
+				for b := a + 1; b < len(matched); b++ {
+					pair := [2]int{matched[a], matched[b]}
+					if !overlaps[pair] {
+						overlaps[pair] = true
+						problems = append(problems, fmt.Errorf("%w: rows %d and %d overlap, e.g. for %s",
+							ErrInvalidTable, pair[0]+1, pair[1]+1, describeSample(elems)))
+					}
+				}
+			}
+		}
+		if len(matched) > 0 {
+			if t.policy == HitFirst || t.policy == HitPriority {
+				matched = t.winners(matched)
+			}
+			for _, i := range matched {
+				used[i] = true
+			}
+		}
+	}
+
+	if gaps > 0 {
+		problems = append(problems, fmt.Errorf("%w: no row matches %d of %d sampled inputs, e.g. %s",
+			ErrInvalidTable, gaps, total, example))
+	}
+	for i, ok := range used {
+		if !ok {
+			problems = append(problems, fmt.Errorf("%w: row %d is never used", ErrInvalidTable, i+1))
+		}
+	}
+
+	return errors.Join(problems...)
+}
+
+// winners returns the rows that make up the decision out of the matching rows,
+// under HitFirst and HitPriority.
+func (t *DecisionTable) winners(matched []int) []int {
+	best := matched[0]
+	if t.policy == HitPriority {
+		for _, i := range matched[1:] {
+			if t.rows[i].Priority > t.rows[best].Priority {
+				best = i
+			}
+		}
+	}
+	return []int{best}
+}
+
+// samples returns the values of the input with the given index worth evaluating
+// the conditions of the table with: the literals the conditions compare it with,
+// values between and around them, and a string none of them mentions.
+func (t *DecisionTable) samples(input int) []any {
+	var numbers []float64
+	var strs []string
+	bools := false
+	for _, row := range t.rows {
+		cell, ok := row.cells[input].(*rule)
+		if !ok {
+			continue
+		}
+		bools = bools || row.bools[input]
+		for _, token := range cell.r {
+			lit, ok := parseLiteral(token)
+			if !ok {
+				continue
+			}
+			switch v := lit.value.(type) {
+			case float64:
+				numbers = append(numbers, v)
+			case string:
+				strs = append(strs, v)
+			}
+		}
+	}
+
+	var samples []any
+	if bools {
+		samples = append(samples, true, false)
+	}
+
+	if len(numbers) > 0 {
+		integral := true
+		for _, n := range numbers {
+			integral = integral && n == math.Trunc(n)
+		}
+		sort.Float64s(numbers)
+
+		points := []float64{numbers[0] - 1}
+		for i, n := range numbers {
+			if integral {
+				points = append(points, n-1, n, n+1)
+			} else if i > 0 {
+				points = append(points, (numbers[i-1]+n)/2, n)
+			} else {
+				points = append(points, n)
+			}
+		}
+		points = append(points, numbers[len(numbers)-1]+1)
+
+		sort.Float64s(points)
+		for i, p := range points {
+			if i > 0 && p == points[i-1] {
+				continue
+			}
+			if integral {
+				samples = append(samples, int64(p))
+			} else {
+				samples = append(samples, p)
+			}
+		}
+	}
+
+	if len(strs) > 0 {
+		sort.Strings(strs)
+		other := ""
+		for i, s := range strs {
+			if i == 0 || s != strs[i-1] {
+				samples = append(samples, s)
+			}
+			if s == other {
+				other += "_"
+			}
+		}
+		samples = append(samples, other)
+	}
+
+	return samples
+}
+
+func describeSample(elems []RuleElement) string {
+	s := make([]string, len(elems))
+	for i, elem := range elems {
+		var value any
+		switch el := elem.(type) {
+		case Attribute:
+			value = el.getValue()
+		case Variable:
+			value = el.getValue()
+		}
+		if str, ok := value.(string); ok {
+			s[i] = fmt.Sprintf("%s=%q", elem.getName(), str)
+		} else {
+			s[i] = fmt.Sprintf("%s=%v", elem.getName(), value)
+		}
+	}
+	return strings.Join(s, ", ")
+}
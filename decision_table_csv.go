@@ -0,0 +1,244 @@
+package rules
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// csvOperators maps the operators allowed in the header of an input column to
+// the operators of rule expressions.
+var csvOperators = map[string]string{
+	"=":  kEQ,
+	"==": kEQ,
+	"!=": kNEQ,
+	"<>": kNEQ,
+	">":  kGT,
+	"<":  kLT,
+	">=": kGTE,
+	"<=": kLTE,
+	kEQ:  kEQ,
+	kNEQ: kNEQ,
+	kGT:  kGT,
+	kLT:  kLT,
+	kGTE: kGTE,
+	kLTE: kLTE,
+}
+
+const (
+	csvIn       = "IN"
+	csvOutput   = "=>"
+	csvPriority = "PRIORITY"
+)
+
+// csvColumn describes a column of a decision table loaded from CSV.
+type csvColumn struct {
+	name string
+	// op is the operator of an input column, IN, or empty for columns holding unary tests.
+	op       string
+	output   bool
+	priority bool
+}
+
+// LoadDecisionTable reads a decision table from CSV. The first record is the
+// header, and each following record is a row. Header cells declare the columns:
+//
+//	age          an input whose cells hold unary tests, as in DecisionRow
+//	age >=       an input compared with the value of its cells; any operator works
+//	country IN   an input equal to one of the values of its cells, separated by ";"
+//	=> class     an output
+//	PRIORITY     the priority of the row, for HitPriority
+//
+// Values that are neither numbers nor TRUE or FALSE are strings, and need no quotes.
+// Empty input cells and "-" match any value. Errors of all rows are reported together,
+// with their line numbers.
+func LoadDecisionTable(r io.Reader, name string, opts ...DecisionTableOption) (*DecisionTable, error) {
+	reader := csv.NewReader(r)
+	reader.Comment = '#'
+	reader.TrimLeadingSpace = true
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("%w: %s has no header", ErrInvalidTable, name)
+		}
+		return nil, err
+	}
+
+	columns := make([]csvColumn, len(header))
+	var inputs, outputs []string
+	for i, cell := range header {
+		column, err := parseCSVColumn(cell)
+		if err != nil {
+			return nil, fmt.Errorf("header, column %d: %w", i+1, err)
+		}
+		columns[i] = column
+		switch {
+		case column.output:
+			outputs = append(outputs, column.name)
+		case !column.priority:
+			inputs = append(inputs, column.name)
+		}
+	}
+
+	table, err := NewDecisionTable(name, inputs, outputs, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	var errs []error
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			var parseErr *csv.ParseError
+			if !errors.As(err, &parseErr) {
+				return nil, err
+			}
+			errs = append(errs, err)
+			continue
+		}
+
+		line, _ := reader.FieldPos(0)
+		row, err := csvRow(columns, record)
+		if err == nil {
+			err = table.AddRow(row)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("line %d: %w", line, err))
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+
+	return table, nil
+}
+
+// LoadDecisionTableFile reads a decision table from the CSV file at path, named
+// after the file without its extension.
+func LoadDecisionTableFile(path string, opts ...DecisionTableOption) (*DecisionTable, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	table, err := LoadDecisionTable(f, name, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return table, nil
+}
+
+func parseCSVColumn(cell string) (csvColumn, error) {
+	fields := strings.Fields(cell)
+	switch {
+	case len(fields) == 1 && strings.EqualFold(fields[0], csvPriority):
+		return csvColumn{priority: true}, nil
+	case len(fields) == 2 && fields[0] == csvOutput:
+		return csvColumn{name: fields[1], output: true}, nil
+	case len(fields) == 2 && strings.HasPrefix(fields[0], csvOutput):
+		return csvColumn{}, fmt.Errorf("%w: expected a space after %s in %q", ErrInvalidTable, csvOutput, cell)
+	case len(fields) == 1:
+		return csvColumn{name: fields[0]}, nil
+	case len(fields) == 2:
+		op := strings.ToUpper(fields[1])
+		if op == csvIn {
+			return csvColumn{name: fields[0], op: csvIn}, nil
+		}
+		if op, ok := csvOperators[op]; ok {
+			return csvColumn{name: fields[0], op: op}, nil
+		}
+		return csvColumn{}, fmt.Errorf("%w: unknown operator %q", ErrInvalidTable, fields[1])
+	default:
+		return csvColumn{}, fmt.Errorf("%w: invalid column %q", ErrInvalidTable, cell)
+	}
+}
+
+// csvRow converts a record into a row of the table.
+func csvRow(columns []csvColumn, record []string) (DecisionRow, error) {
+	if len(record) != len(columns) {
+		return DecisionRow{}, fmt.Errorf("%w: %d cells, expected %d", ErrInvalidTable, len(record), len(columns))
+	}
+
+	var row DecisionRow
+	for i, column := range columns {
+		cell := strings.TrimSpace(record[i])
+		switch {
+		case column.priority:
+			if cell == "" {
+				continue
+			}
+			p, err := strconv.Atoi(cell)
+			if err != nil {
+				return DecisionRow{}, fmt.Errorf("%w: invalid priority %q", ErrInvalidTable, cell)
+			}
+			row.Priority = p
+		case column.output:
+			row.Outputs = append(row.Outputs, csvOutputValue(cell))
+		default:
+			row.Conditions = append(row.Conditions, csvCondition(column, cell))
+		}
+	}
+	return row, nil
+}
+
+// csvCondition converts an input cell into the unary test of a row.
+func csvCondition(column csvColumn, cell string) string {
+	if cell == "" || cell == "-" || column.op == "" {
+		return cell
+	}
+	if column.op == kEQ && (cell == "TRUE" || cell == "FALSE") {
+		return cell
+	}
+	if column.op != csvIn {
+		return column.op + " " + csvLiteral(cell)
+	}
+
+	values := strings.Split(cell, ";")
+	for i, v := range values {
+		values[i] = csvLiteral(strings.TrimSpace(v))
+	}
+	return strings.Join(values, ", ")
+}
+
+// csvLiteral converts a value of a cell into a literal token.
+func csvLiteral(value string) string {
+	if value == "TRUE" || value == "FALSE" || isLiteral(value) {
+		return value
+	}
+	return strconv.Quote(value)
+}
+
+// csvOutputValue converts an output cell into a value: a boolean, a number or a string.
+func csvOutputValue(cell string) any {
+	switch {
+	case cell == "":
+		return nil
+	case cell == "TRUE":
+		return true
+	case cell == "FALSE":
+		return false
+	case isNumberLiteral(cell):
+		if i, err := strconv.ParseInt(cell, 10, 64); err == nil {
+			return i
+		}
+		f, _ := strconv.ParseFloat(cell, 64)
+		return f
+	case isStringLiteral(cell):
+		if s, err := strconv.Unquote(cell); err == nil {
+			return s
+		}
+	}
+	return cell
+}
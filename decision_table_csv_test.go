@@ -0,0 +1,73 @@
+package rules
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestLoadDecisionTableFile(t *testing.T) {
+	table, err := LoadDecisionTableFile("testdata/tables/fare_class.csv", WithHitPolicy(HitPriority))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if table.Name() != "fare_class" {
+		t.Errorf("expected table named fare_class, got %s", table.Name())
+	}
+
+	tests := []struct {
+		age      int
+		country  string
+		nights   int
+		member   bool
+		class    string
+		discount float64
+	}{
+		{age: 10, country: "pl", nights: 3, member: true, class: "child", discount: 50},
+		{age: 30, country: "de", nights: 7, member: true, class: "local member", discount: 15.5},
+		{age: 30, country: "de", nights: 8, member: true, class: "local", discount: 10},
+		{age: 30, country: "fr", nights: 8, member: true, class: "long stay", discount: 5},
+		{age: 30, country: "fr", nights: 31, member: false, class: "standard", discount: 0},
+	}
+	for _, tt := range tests {
+		d, err := table.Evaluate(NewContext(
+			NewVariable[int]("age")(tt.age),
+			NewVariable[string]("country")(tt.country),
+			NewVariable[int]("nights")(tt.nights),
+			NewAttribute("member")(tt.member),
+		))
+		if err != nil {
+			t.Fatal(err)
+		}
+		class, err := Output[string](d, "class")
+		if err != nil || class != tt.class {
+			t.Errorf("%+v: expected class %q, got %q (%v)", tt, tt.class, class, err)
+		}
+		discount, err := Output[float64](d, "discount")
+		if err != nil || discount != tt.discount {
+			t.Errorf("%+v: expected discount %v, got %v (%v)", tt, tt.discount, discount, err)
+		}
+	}
+}
+
+func TestLoadDecisionTableErrors(t *testing.T) {
+	_, err := LoadDecisionTableFile("testdata/tables/broken.csv")
+	if !errors.Is(err, ErrInvalidTable) {
+		t.Fatalf("expected ErrInvalidTable, got %v", err)
+	}
+	for _, want := range []string{"line 3: invalid decision table: invalid priority", "line 4:", "line 5:"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected %q in %v", want, err)
+		}
+	}
+	if strings.Contains(err.Error(), "line 2:") {
+		t.Errorf("expected line 2 to load, got %v", err)
+	}
+
+	headers := []string{"", "age ~", "=>class", "age >= 18"}
+	for _, header := range headers {
+		if _, err := LoadDecisionTable(strings.NewReader(header+"\n"), "t"); err == nil {
+			t.Errorf("expected an error for header %q", header)
+		}
+	}
+}
@@ -0,0 +1,265 @@
+package rules
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func fareTable(t *testing.T, policy HitPolicy, rows ...DecisionRow) *DecisionTable {
+	t.Helper()
+	table, err := NewDecisionTable("fare", []string{"age", "country", "member"}, []string{"class", "discount"}, WithHitPolicy(policy))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, row := range rows {
+		if err := table.AddRow(row); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return table
+}
+
+func fareContext(age int, country string, member bool) RuleContext {
+	return NewContext(
+		NewVariable[int]("age")(age),
+		NewVariable[string]("country")(country),
+		NewAttribute("member")(member),
+	)
+}
+
+func TestDecisionTableHitPolicies(t *testing.T) {
+	rows := []DecisionRow{
+		{Conditions: []string{"LT 18", "-", "-"}, Outputs: []any{"child", 50}},
+		{Conditions: []string{"GTE 18", `"pl", "de"`, "TRUE"}, Outputs: []any{"member", 20}, Priority: 2},
+		{Conditions: []string{"GTE 18", "-", "-"}, Outputs: []any{"adult", 0}, Priority: 1},
+	}
+
+	tests := []struct {
+		policy HitPolicy
+		ctx    RuleContext
+		rows   []int
+		class  []any
+		disc   []any
+		err    error
+	}{
+		{policy: HitUnique, ctx: fareContext(10, "pl", true), rows: []int{0}, class: []any{"child"}, disc: []any{50}},
+		{policy: HitUnique, ctx: fareContext(30, "pl", true), err: ErrHitPolicy},
+		{policy: HitFirst, ctx: fareContext(30, "de", true), rows: []int{1}, class: []any{"member"}, disc: []any{20}},
+		{policy: HitFirst, ctx: fareContext(30, "fr", true), rows: []int{2}, class: []any{"adult"}, disc: []any{0}},
+		{policy: HitPriority, ctx: fareContext(30, "pl", true), rows: []int{1}, class: []any{"member"}, disc: []any{20}},
+		{policy: HitCollect, ctx: fareContext(30, "pl", true), rows: []int{1, 2}, class: []any{"member", "adult"}, disc: []any{20, 0}},
+		{policy: HitCollectSum, ctx: fareContext(30, "pl", true), err: ErrInvalidTable},
+		{policy: HitCollectMax, ctx: fareContext(30, "pl", true), rows: []int{1, 2}, class: []any{"member"}, disc: []any{20}},
+		{policy: HitCollectMin, ctx: fareContext(30, "pl", true), rows: []int{1, 2}, class: []any{"adult"}, disc: []any{0}},
+		{policy: HitCollectCount, ctx: fareContext(30, "pl", false), rows: []int{2}, class: []any{1}, disc: []any{1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.policy.String(), func(t *testing.T) {
+			d, err := fareTable(t, tt.policy, rows...).Evaluate(tt.ctx)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("expected %v, got %v", tt.err, err)
+			}
+			if err != nil {
+				return
+			}
+			if !reflect.DeepEqual(d.Rows, tt.rows) {
+				t.Errorf("expected rows %v, got %v", tt.rows, d.Rows)
+			}
+			if tt.class != nil && !reflect.DeepEqual(d.Values("class"), tt.class) {
+				t.Errorf("expected class %v, got %v", tt.class, d.Values("class"))
+			}
+			if !reflect.DeepEqual(d.Values("discount"), tt.disc) {
+				t.Errorf("expected discount %v, got %v", tt.disc, d.Values("discount"))
+			}
+		})
+	}
+}
+
+func TestDecisionTableSum(t *testing.T) {
+	table, err := NewDecisionTable("surcharge", []string{"bags", "pet"}, []string{"fee"}, WithHitPolicy(HitCollectSum))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, row := range []DecisionRow{
+		{Conditions: []string{"GT 1", "-"}, Outputs: []any{25}},
+		{Conditions: []string{"GT 2", "-"}, Outputs: []any{40}},
+		{Conditions: []string{"-", "TRUE"}, Outputs: []any{uint8(60)}},
+	} {
+		if err := table.AddRow(row); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	d, err := table.Evaluate(NewContext(NewVariable[int]("bags")(3), NewAttribute("pet")(true)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fee, err := Output[int](d, "fee"); err != nil || fee != 125 {
+		t.Errorf("expected 125, got %v (%v)", fee, err)
+	}
+}
+
+func TestDecisionTableCount(t *testing.T) {
+	table := fareTable(t, HitCollectCount,
+		DecisionRow{Conditions: []string{"LT 18", "-", "-"}, Outputs: []any{"child", nil}},
+		DecisionRow{Conditions: []string{"LT 26", "-", "-"}, Outputs: []any{"youth", 12.5}},
+	)
+
+	tests := []struct {
+		age  int
+		want int
+	}{
+		{age: 10, want: 2},
+		{age: 20, want: 1},
+		{age: 30, want: 0},
+	}
+	for _, tt := range tests {
+		d, err := table.Evaluate(fareContext(tt.age, "pl", false))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, output := range []string{"class", "discount"} {
+			if got, err := Output[int](d, output); err != nil || got != tt.want {
+				t.Errorf("age %d: expected %s count %d, got %v (%v)", tt.age, output, tt.want, got, err)
+			}
+		}
+	}
+}
+
+func TestDecisionTableOutput(t *testing.T) {
+	table := fareTable(t, HitFirst,
+		DecisionRow{Conditions: []string{"LT 18", "-", "-"}, Outputs: []any{"child", 50}},
+		DecisionRow{Conditions: []string{"LT 26", "-", "-"}, Outputs: []any{"youth", 12.5}},
+	)
+
+	d, err := table.Evaluate(fareContext(10, "pl", false))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if class, err := Output[string](d, "class"); err != nil || class != "child" {
+		t.Errorf("expected child, got %q (%v)", class, err)
+	}
+	if discount, err := Output[float64](d, "discount"); err != nil || discount != 50 {
+		t.Errorf("expected 50, got %v (%v)", discount, err)
+	}
+	type fareClass string
+	if class, err := Output[fareClass](d, "class"); err != nil || class != "child" {
+		t.Errorf("expected a string to convert to a named string type, got %q (%v)", class, err)
+	}
+
+	d, err = table.Evaluate(fareContext(20, "pl", false))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Output[int](d, "discount"); !errors.Is(err, ErrInvalidTable) {
+		t.Errorf("expected 12.5 not to convert to int, got %v", err)
+	}
+	if _, err := Output[int](d, "class"); !errors.Is(err, ErrInvalidTable) {
+		t.Errorf("expected a string not to convert to int, got %v", err)
+	}
+
+	d, err = table.Evaluate(fareContext(30, "pl", false))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Output[string](d, "class"); !errors.Is(err, ErrNoMatch) {
+		t.Errorf("expected ErrNoMatch, got %v", err)
+	}
+}
+
+func TestDecisionTableMultiByteCells(t *testing.T) {
+	table, err := NewDecisionTable("city", []string{"city"}, []string{"region"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows := []DecisionRow{
+		{Conditions: []string{`"Kraków", "Łódź"`}, Outputs: []any{"south"}},
+		{Conditions: []string{`"ąąąąą", "b"`}, Outputs: []any{"other"}},
+	}
+	for _, row := range rows {
+		if err := table.AddRow(row); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		city   string
+		region string
+	}{
+		{city: "Łódź", region: "south"},
+		{city: "Kraków", region: "south"},
+		{city: "ąąąąą", region: "other"},
+		{city: "b", region: "other"},
+	}
+	for _, tt := range tests {
+		d, err := table.Evaluate(NewContext(NewVariable[string]("city")(tt.city)))
+		if err != nil {
+			t.Fatalf("%s: %v", tt.city, err)
+		}
+		if got, _ := Output[string](d, "region"); got != tt.region {
+			t.Errorf("%s: expected %q, got %q", tt.city, tt.region, got)
+		}
+	}
+}
+
+func TestDecisionTableAddRow(t *testing.T) {
+	tests := []struct {
+		name string
+		row  DecisionRow
+	}{
+		{"missing condition", DecisionRow{Conditions: []string{"-", "-"}, Outputs: []any{"a", 1}}},
+		{"missing output", DecisionRow{Conditions: []string{"-", "-", "-"}, Outputs: []any{"a"}}},
+		{"invalid test", DecisionRow{Conditions: []string{"GTE", "-", "-"}, Outputs: []any{"a", 1}}},
+		{"empty test", DecisionRow{Conditions: []string{"1,", "-", "-"}, Outputs: []any{"a", 1}}},
+		{"duplicate", DecisionRow{Conditions: []string{"GTE 18", "-", "-"}, Outputs: []any{"b", 2}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			table := fareTable(t, HitUnique, DecisionRow{Conditions: []string{"GTE 18", "", "-"}, Outputs: []any{"a", 1}})
+			if err := table.AddRow(tt.row); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+
+	if _, err := NewDecisionTable("t", []string{"a"}, []string{"a"}); !errors.Is(err, ErrInvalidTable) {
+		t.Errorf("expected ErrInvalidTable for a duplicate column, got %v", err)
+	}
+}
+
+func TestDecisionTableValidate(t *testing.T) {
+	complete := fareTable(t, HitUnique,
+		DecisionRow{Conditions: []string{"LT 18", "-", "-"}, Outputs: []any{"child", 50}},
+		DecisionRow{Conditions: []string{"GTE 18", `"pl"`, "-"}, Outputs: []any{"local", 10}},
+		DecisionRow{Conditions: []string{"GTE 18", `NEQ "pl"`, "-"}, Outputs: []any{"adult", 0}},
+	)
+	if err := complete.Validate(); err != nil {
+		t.Errorf("expected no problems, got %v", err)
+	}
+
+	broken := fareTable(t, HitUnique,
+		DecisionRow{Conditions: []string{"LT 18", "-", "-"}, Outputs: []any{"child", 50}},
+		DecisionRow{Conditions: []string{"GTE 16", `"pl"`, "TRUE"}, Outputs: []any{"member", 20}},
+		DecisionRow{Conditions: []string{"GT 20", "-", "-"}, Outputs: []any{"adult", 0}},
+	)
+	err := broken.Validate()
+	if !errors.Is(err, ErrInvalidTable) {
+		t.Fatalf("expected ErrInvalidTable, got %v", err)
+	}
+	for _, want := range []string{"rows 1 and 2 overlap", "rows 2 and 3 overlap", "no row matches", "age=18"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected %q in %v", want, err)
+		}
+	}
+
+	shadowed := fareTable(t, HitFirst,
+		DecisionRow{Conditions: []string{"GTE 18", "-", "-"}, Outputs: []any{"adult", 0}},
+		DecisionRow{Conditions: []string{"GTE 65", "-", "-"}, Outputs: []any{"senior", 30}},
+		DecisionRow{Conditions: []string{"-", "-", "-"}, Outputs: []any{"child", 50}},
+	)
+	if err := shadowed.Validate(); err == nil || !strings.Contains(err.Error(), "row 2 is never used") {
+		t.Errorf("expected row 2 to be reported, got %v", err)
+	}
+}
@@ -0,0 +1,286 @@
+package rules
+
+import (
+	"bufio"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// maxMacroDepth limits how deep macros can expand into other macros,
+// so that a macro using itself is reported instead of expanding forever.
+const maxMacroDepth = 32
+
+// Definitions holds named constants and parameterised macros that Parse expands
+// in place when an expression is compiled with WithDefinitions.
+//
+// A constant is replaced by its value wherever its name is used:
+//
+//	MAX_CARRY_ON = 7
+//
+// A macro is replaced by its body, with every parameter substituted by the
+// corresponding argument of the call:
+//
+//	inRange(x, lo, hi) = x GTE lo AND x LTE hi
+type Definitions struct {
+	constants map[string]string
+	macros    map[string]*macro
+}
+
+type macro struct {
+	params []string
+	body   []string
+}
+
+// NewDefinitions creates an empty set of definitions.
+func NewDefinitions() *Definitions {
+	return &Definitions{
+		constants: make(map[string]string),
+		macros:    make(map[string]*macro),
+	}
+}
+
+// ParseDefinitions parses definitions written one per line, as NAME = value for
+// constants and name(params) = expression for macros. Empty lines and lines
+// starting with // are ignored.
+func ParseDefinitions(src string) (*Definitions, error) {
+	d := NewDefinitions()
+
+	scanner := bufio.NewScanner(strings.NewReader(src))
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "//") {
+			continue
+		}
+		if err := d.define(text); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return d, nil
+}
+
+func (d *Definitions) define(text string) error {
+	lhs, rhs, ok := strings.Cut(text, "=")
+	if !ok {
+		return fmt.Errorf("%w: expected a definition, got %q", ErrInvalidDefinition, text)
+	}
+	lhs = strings.TrimSpace(lhs)
+
+	name, params, isMacro := strings.Cut(lhs, "(")
+	if !isMacro {
+		tokens, err := tokenize(rhs)
+		if err != nil {
+			return err
+		}
+		if len(tokens) != 1 {
+			return fmt.Errorf("%w: value of %s must be a single literal or constant", ErrInvalidDefinition, lhs)
+		}
+		return d.defineConstant(lhs, tokens[0])
+	}
+
+	params, ok = strings.CutSuffix(strings.TrimSpace(params), ")")
+	if !ok {
+		return fmt.Errorf("%w: invalid macro signature %q", ErrInvalidDefinition, lhs)
+	}
+	var names []string
+	if strings.TrimSpace(params) != "" {
+		for _, p := range strings.Split(params, ",") {
+			names = append(names, strings.TrimSpace(p))
+		}
+	}
+
+	return d.DefineMacro(strings.TrimSpace(name), names, rhs)
+}
+
+// DefineConstant defines a constant with a string or numeric value.
+func (d *Definitions) DefineConstant(name string, value any) error {
+	v := reflect.ValueOf(value)
+	switch {
+	case !v.IsValid():
+		return fmt.Errorf("%w: constant %s has no value", ErrInvalidDefinition, name)
+	case v.Kind() == reflect.String:
+		return d.defineConstant(name, strconv.Quote(v.String()))
+	case isInt(v.Kind()):
+		return d.defineConstant(name, strconv.FormatInt(v.Int(), 10))
+	case isUint(v.Kind()):
+		return d.defineConstant(name, strconv.FormatUint(v.Uint(), 10))
+	case isNumber(v.Kind()):
+		return d.defineConstant(name, strconv.FormatFloat(v.Float(), 'f', -1, 64))
+	default:
+		return fmt.Errorf("%w: constant %s must be a string or a number, got %T", ErrInvalidDefinition, name, value)
+	}
+}
+
+func (d *Definitions) defineConstant(name, token string) error {
+	if err := d.checkName(name); err != nil {
+		return err
+	}
+	if c, ok := d.constants[token]; ok {
+		token = c
+	}
+	if !isLiteral(token) {
+		return fmt.Errorf("%w: value of %s must be a literal or constant, got %s", ErrInvalidDefinition, name, token)
+	}
+	d.constants[name] = token
+	return nil
+}
+
+// DefineMacro defines a macro with the given parameters. The body is an expression
+// which can use the parameters, constants and other macros.
+func (d *Definitions) DefineMacro(name string, params []string, body string) error {
+	if err := d.checkName(name); err != nil {
+		return err
+	}
+	seen := map[string]bool{}
+	for _, p := range params {
+		if !isName(p) || seen[p] {
+			return fmt.Errorf("%w: invalid parameter %q of %s", ErrInvalidDefinition, p, name)
+		}
+		seen[p] = true
+	}
+
+	tokens, err := tokenize(body)
+	if err != nil {
+		return fmt.Errorf("%w: %s: %w", ErrInvalidDefinition, name, err)
+	}
+	if len(tokens) == 0 || !isValid(parse(tokens)) {
+		return fmt.Errorf("%w: invalid body of %s", ErrInvalidDefinition, name)
+	}
+
+	d.macros[name] = &macro{params: params, body: tokens}
+	return nil
+}
+
+func (d *Definitions) checkName(name string) error {
+	if !isName(name) {
+		return fmt.Errorf("%w: invalid name %q", ErrInvalidDefinition, name)
+	}
+	_, isConstant := d.constants[name]
+	_, isMacro := d.macros[name]
+	if isConstant || isMacro {
+		return fmt.Errorf("%w: %s is already defined", ErrInvalidDefinition, name)
+	}
+	return nil
+}
+
+// isName reports whether s can be used as a name of an element, constant or macro.
+func isName(s string) bool {
+	if s == "" || isOperator(s) || unicode.IsDigit([]rune(s)[0]) {
+		return false
+	}
+	for _, r := range s {
+		if !isIdentRune(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// WithDefinitions expands the constants and macros of d in the expression.
+func WithDefinitions(d *Definitions) ParseOption {
+	return func(c *parseConfig) {
+		c.definitions = d
+	}
+}
+
+// expand replaces constants and macro calls in tokens. Tokens coming from a macro
+// body take the position of the macro call.
+func (d *Definitions) expand(tokens []token, depth int) ([]token, error) {
+	if d == nil {
+		return tokens, nil
+	}
+	if depth > maxMacroDepth {
+		return nil, fmt.Errorf("%w: macros nested deeper than %d", ErrCyclicDependency, maxMacroDepth)
+	}
+
+	out := make([]token, 0, len(tokens))
+	for i := 0; i < len(tokens); i++ {
+		tok := tokens[i]
+
+		m, ok := d.macros[tok.text]
+		if !ok || i+1 >= len(tokens) || tokens[i+1].text != "(" {
+			if c, ok := d.constants[tok.text]; ok {
+				tok.text = c
+			}
+			out = append(out, tok)
+			continue
+		}
+
+		args, end := splitArguments(tokens, i+1)
+		if len(args) != len(m.params) {
+			return nil, fmt.Errorf("%w: %s expects %d arguments, got %d", ErrInvalidExpression, tok.text, len(m.params), len(args))
+		}
+
+		open := token{text: "(", pos: tok.pos, expanded: true}
+		body := make([]token, 0, len(m.body))
+		for _, t := range m.body {
+			if p := indexOf(m.params, t); p >= 0 {
+				body = append(body, open)
+				body = append(body, args[p]...)
+				body = append(body, token{text: ")", pos: tok.pos, expanded: true})
+				continue
+			}
+			body = append(body, token{text: t, pos: tok.pos, expanded: true})
+		}
+
+		expanded, err := d.expand(body, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, token{text: "(", pos: tok.pos, comments: tok.comments, expanded: true})
+		out = append(out, expanded...)
+		out = append(out, token{text: ")", pos: tokens[end].pos, expanded: true})
+		i = end
+	}
+
+	return out, nil
+}
+
+// splitArguments splits the argument list opened by the parenthesis at tokens[open]
+// on its top level commas. It returns the arguments and the index of the closing parenthesis.
+func splitArguments(tokens []token, open int) ([][]token, int) {
+	var args [][]token
+	var current []token
+	depth := 0
+
+	for i := open + 1; i < len(tokens); i++ {
+		switch tokens[i].text {
+		case "(":
+			depth++
+		case ")":
+			if depth == 0 {
+				if len(current) > 0 || len(args) > 0 {
+					args = append(args, current)
+				}
+				return args, i
+			}
+			depth--
+		case ",":
+			if depth == 0 {
+				args = append(args, current)
+				current = nil
+				continue
+			}
+		}
+		current = append(current, tokens[i])
+	}
+
+	return args, len(tokens) - 1
+}
+
+func indexOf(s []string, v string) int {
+	for i, e := range s {
+		if e == v {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,105 @@
+package rules
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestDefinitions(t *testing.T) {
+	defs, err := ParseDefinitions(`
+		// carry-on limits
+		MAX_CARRY_ON = 7
+		LIMIT = MAX_CARRY_ON
+		HOME = "pl"
+
+		inRange(x, lo, hi) = x GTE lo AND x LTE hi
+		isHome(c) = LOWER(c) EQ HOME
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := defs.DefineConstant("MIN_AGE", 18); err != nil {
+		t.Fatal(err)
+	}
+
+	var weight = NewVariable[float64]("weight")
+	var age = NewVariable[int]("age")
+	var country = NewVariable[string]("country")
+
+	tests := []struct {
+		rule   string
+		ctx    RuleContext
+		want   bool
+		string string
+	}{
+		{
+			rule:   "weight LTE LIMIT",
+			ctx:    NewContext(weight(6.5)),
+			want:   true,
+			string: "weight LTE 7",
+		},
+		{
+			rule:   "inRange(age, MIN_AGE, 65) AND isHome(country)",
+			ctx:    NewContext(age(30), country("PL")),
+			want:   true,
+			string: `age GTE 18 AND age LTE 65 AND LOWER(country) EQ "pl"`,
+		},
+		{
+			rule:   "inRange(weight, 0, MAX_CARRY_ON)",
+			ctx:    NewContext(weight(7.5)),
+			want:   false,
+			string: "weight GTE 0 AND weight LTE 7",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.rule, func(t *testing.T) {
+			r, err := Parse("rule", tt.rule, WithDefinitions(defs))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if s := r.(*rule).String(); s != tt.string {
+				t.Errorf("String() = %v, want %v", s, tt.string)
+			}
+			got, err := r.Evaluate(tt.ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("Evaluate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDefinitionsErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		err  error
+	}{
+		{name: "not a definition", src: "MAX 7", err: ErrInvalidDefinition},
+		{name: "duplicate", src: "MAX = 7\nMAX = 8", err: ErrInvalidDefinition},
+		{name: "not a literal", src: "MAX = a AND b", err: ErrInvalidDefinition},
+		{name: "operator name", src: "AND = 1", err: ErrInvalidDefinition},
+		{name: "invalid body", src: "f(x) = x AND", err: ErrInvalidDefinition},
+		{name: "duplicate parameter", src: "f(x, x) = x", err: ErrInvalidDefinition},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseDefinitions(tt.src); !errors.Is(err, tt.err) {
+				t.Errorf("ParseDefinitions() error = %v, want %v", err, tt.err)
+			}
+		})
+	}
+
+	defs, err := ParseDefinitions("f(x) = g(x)\ng(x) = f(x)\nh(x, y) = x AND y")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Parse("rule", "f(a)", WithDefinitions(defs)); !errors.Is(err, ErrCyclicDependency) {
+		t.Errorf("Parse() error = %v, want %v", err, ErrCyclicDependency)
+	}
+	if _, err := Parse("rule", "h(a)", WithDefinitions(defs)); !errors.Is(err, ErrInvalidExpression) {
+		t.Errorf("Parse() error = %v, want %v", err, ErrInvalidExpression)
+	}
+}
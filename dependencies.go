@@ -0,0 +1,186 @@
+package rules
+
+import (
+	"sort"
+)
+
+// Dependencies returns the names of the context elements the rule reads, sorted.
+// Names of the rules it references through its registry are left out.
+func (r *rule) Dependencies() []string {
+	elements, _ := r.dependencies(nil)
+	sort.Strings(elements)
+	return elements
+}
+
+// DependenciesOf returns the names of the context elements r reads, sorted, or nil
+// for rules not created by Parse.
+func DependenciesOf(r Rule) []string {
+	if d, ok := r.(interface{ Dependencies() []string }); ok {
+		return d.Dependencies()
+	}
+	return nil
+}
+
+// dependencies returns the names of the elements r reads and the rules it references,
+// in the order they first appear. Names are resolved as rules with the registry of
+// r, then with resolvers.
+func (r *rule) dependencies(resolvers []ruleResolver) ([]string, []Rule) {
+	var elements []string
+	var refs []Rule
+	for _, name := range identifiers(r) {
+		if ref, ok := r.lookupReference(name, resolvers); ok {
+			refs = append(refs, ref)
+			continue
+		}
+		elements = append(elements, name)
+	}
+	return elements, refs
+}
+
+// lookupReference returns the rule named name, if r references one through its
+// registry or resolvers.
+func (r *rule) lookupReference(name string, resolvers []ruleResolver) (Rule, bool) {
+	if r.references != nil {
+		if ref, ok := r.references.lookupRule(name); ok {
+			return ref, true
+		}
+	}
+	for _, resolver := range resolvers {
+		if ref, ok := resolver.lookupRule(name); ok {
+			return ref, true
+		}
+	}
+	return nil, false
+}
+
+// DependencyGraph relates the rules of a set to the context elements they read and
+// to the rules they reference. It answers which rules are affected when elements
+// change.
+type DependencyGraph struct {
+	rules []string
+	// elements and references hold the elements read and the rules referenced
+	// directly by each rule.
+	elements   map[string][]string
+	references map[string][]string
+
+	// byName and resolvers are used to evaluate the rules when drawing the graph.
+	byName    map[string]Rule
+	resolvers []ruleResolver
+}
+
+// NewDependencyGraph builds the dependency graph of the rules of set, including the
+// rules they reference from outside the set. Names are resolved as rules with the
+// registries of the rules, then with the set, as when the set is evaluated.
+func NewDependencyGraph(set RuleSet) *DependencyGraph {
+	g := &DependencyGraph{
+		elements:   make(map[string][]string),
+		references: make(map[string][]string),
+		byName:     make(map[string]Rule),
+	}
+	if resolver, ok := set.(ruleResolver); ok {
+		g.resolvers = append(g.resolvers, resolver)
+	}
+	for _, r := range set.Rules() {
+		g.add(r, g.resolvers)
+	}
+	return g
+}
+
+func (g *DependencyGraph) add(r Rule, resolvers []ruleResolver) {
+	name := r.Name()
+	if _, ok := g.elements[name]; ok {
+		return
+	}
+	g.rules = append(g.rules, name)
+	g.byName[name] = r
+
+	rr, ok := r.(*rule)
+	if !ok {
+		g.elements[name] = DependenciesOf(r)
+		return
+	}
+	elements, refs := rr.dependencies(resolvers)
+	sort.Strings(elements)
+	g.elements[name] = elements
+	for _, ref := range refs {
+		g.references[name] = append(g.references[name], ref.Name())
+		g.add(ref, resolvers)
+	}
+	sort.Strings(g.references[name])
+}
+
+// Rules returns the names of the rules of the graph: the rules of the set in
+// evaluation order, each directly followed by the rules it references that are not
+// listed yet, depth first, whether they belong to the set or not.
+func (g *DependencyGraph) Rules() []string {
+	return append([]string(nil), g.rules...)
+}
+
+// Elements returns the names of the elements read by the rules of the graph, sorted.
+func (g *DependencyGraph) Elements() []string {
+	seen := make(map[string]bool)
+	var elements []string
+	for _, name := range g.rules {
+		for _, e := range g.elements[name] {
+			if !seen[e] {
+				seen[e] = true
+				elements = append(elements, e)
+			}
+		}
+	}
+	sort.Strings(elements)
+	return elements
+}
+
+// ElementsOf returns the names of the elements the named rule reads directly, sorted.
+func (g *DependencyGraph) ElementsOf(rule string) []string {
+	return append([]string(nil), g.elements[rule]...)
+}
+
+// ReferencesOf returns the names of the rules the named rule references directly, sorted.
+func (g *DependencyGraph) ReferencesOf(rule string) []string {
+	return append([]string(nil), g.references[rule]...)
+}
+
+// Affected returns the names of the rules whose results may change when the named
+// elements change: the rules reading them, and the rules referencing those. They are
+// listed in the same order as in Rules, so referenced rules follow the first rule
+// referencing them.
+func (g *DependencyGraph) Affected(elements ...string) []string {
+	changed := make(map[string]bool, len(elements))
+	for _, e := range elements {
+		changed[e] = true
+	}
+
+	affected := make(map[string]bool)
+	for progress := true; progress; {
+		progress = false
+		for _, name := range g.rules {
+			if affected[name] {
+				continue
+			}
+			if containsAny(g.elements[name], changed) || containsAny(g.references[name], affected) {
+				affected[name] = true
+				progress = true
+			}
+		}
+	}
+
+	var names []string
+	for _, name := range g.rules {
+		if affected[name] {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// containsAny reports whether any of names is in set.
+func containsAny(names []string, set map[string]bool) bool {
+	for _, name := range names {
+		if set[name] {
+			return true
+		}
+	}
+	return false
+}
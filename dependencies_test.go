@@ -0,0 +1,79 @@
+package rules
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDependencies(t *testing.T) {
+	registry, err := NewRuleRegistry(MustParse("adult", "age GTE 18"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := MustParse("upgrade", `adult AND (gold OR LOWER(country) EQ "pl") AND NOT gold`, WithRules(registry))
+	if got, want := DependenciesOf(r), []string{"country", "gold"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+	if got := DependenciesOf(constantRule(true)); got != nil {
+		t.Errorf("expected no dependencies, got %v", got)
+	}
+}
+
+func TestDependencyGraph(t *testing.T) {
+	registry, err := NewRuleRegistry(MustParse("adult", "age GTE 18"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	set := NewRuleSet(
+		MustParse("eligible", "adult AND NOT blacklisted", WithRules(registry)),
+		MustParse("upgrade", "eligible AND (gold OR miles GT 1000)"),
+		MustParse("lounge", "gold"),
+		constantRule(true),
+	)
+	g := NewDependencyGraph(set)
+
+	if got, want := g.Rules(), []string{"eligible", "adult", "upgrade", "lounge", "constant"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("expected rules %v, got %v", want, got)
+	}
+	if got, want := g.Elements(), []string{"age", "blacklisted", "gold", "miles"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("expected elements %v, got %v", want, got)
+	}
+	if got, want := g.ElementsOf("upgrade"), []string{"gold", "miles"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("expected elements of upgrade %v, got %v", want, got)
+	}
+	if got, want := g.ReferencesOf("upgrade"), []string{"eligible"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("expected references of upgrade %v, got %v", want, got)
+	}
+
+	tests := []struct {
+		elements []string
+		want     []string
+	}{
+		{elements: []string{"age"}, want: []string{"eligible", "adult", "upgrade"}},
+		{elements: []string{"gold"}, want: []string{"upgrade", "lounge"}},
+		{elements: []string{"miles", "blacklisted"}, want: []string{"eligible", "upgrade"}},
+		{elements: []string{"unknown"}},
+	}
+	for _, tt := range tests {
+		if got := g.Affected(tt.elements...); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Affected(%v): expected %v, got %v", tt.elements, tt.want, got)
+		}
+	}
+}
+
+func TestDependencyGraphOrder(t *testing.T) {
+	set := NewRuleSet(
+		MustParse("upgrade", "eligible AND gold"),
+		MustParse("lounge", "gold"),
+		MustParse("eligible", "NOT blacklisted"),
+	)
+	g := NewDependencyGraph(set)
+
+	// Referenced rules directly follow the first rule referencing them.
+	if got, want := g.Rules(), []string{"upgrade", "eligible", "lounge"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("expected rules %v, got %v", want, got)
+	}
+	if got, want := g.Affected("blacklisted", "gold"), []string{"upgrade", "eligible", "lounge"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("expected affected rules %v, got %v", want, got)
+	}
+}
@@ -0,0 +1,336 @@
+package rules
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// DiagramOption configures diagrams of rules and dependency graphs.
+type DiagramOption func(*diagramConfig)
+
+type diagramConfig struct {
+	ctx RuleContext
+}
+
+// WithEvaluation colours the nodes of a diagram with their results for ctx: green
+// when true, red when false and grey when they cannot be evaluated. The values of
+// variables are added to their labels.
+func WithEvaluation(ctx RuleContext) DiagramOption {
+	return func(c *diagramConfig) {
+		c.ctx = ctx
+	}
+}
+
+// ToDOT renders the expression tree of r as a Graphviz DOT digraph. Chains of AND and
+// OR are drawn as single nodes, and comparisons as leaves.
+func ToDOT(r Rule, opts ...DiagramOption) (string, error) {
+	d, err := ruleDiagram(r, opts)
+	if err != nil {
+		return "", err
+	}
+	return d.dot(), nil
+}
+
+// ToMermaid renders the expression tree of r as a Mermaid flowchart, like ToDOT.
+func ToMermaid(r Rule, opts ...DiagramOption) (string, error) {
+	d, err := ruleDiagram(r, opts)
+	if err != nil {
+		return "", err
+	}
+	return d.mermaid(), nil
+}
+
+// DOT renders the graph as a Graphviz DOT digraph, with edges from rules to the
+// elements they read and the rules they reference.
+func (g *DependencyGraph) DOT(opts ...DiagramOption) string {
+	return g.diagram(opts).dot()
+}
+
+// Mermaid renders the graph as a Mermaid flowchart, like DOT.
+func (g *DependencyGraph) Mermaid(opts ...DiagramOption) string {
+	return g.diagram(opts).mermaid()
+}
+
+// diagram is a directed graph to render.
+type diagram struct {
+	title string
+	nodes []diagramNode
+	edges [][2]int
+}
+
+type diagramNode struct {
+	label string
+	shape diagramShape
+	// state is the result of the node in an evaluation, if any.
+	state diagramState
+}
+
+type diagramShape int
+
+const (
+	shapeOperator diagramShape = iota
+	shapeCondition
+	shapeRule
+	shapeElement
+)
+
+type diagramState int
+
+const (
+	stateNone diagramState = iota
+	stateTrue
+	stateFalse
+	stateError
+)
+
+// diagramColors holds the fill and stroke colours of the states.
+var diagramColors = map[diagramState][2]string{
+	stateTrue:  {"#c8e6c9", "#2e7d32"},
+	stateFalse: {"#ffcdd2", "#c62828"},
+	stateError: {"#eeeeee", "#9e9e9e"},
+}
+
+func (d *diagram) add(label string, shape diagramShape) int {
+	d.nodes = append(d.nodes, diagramNode{label: label, shape: shape})
+	return len(d.nodes) - 1
+}
+
+// evaluated sets the state of node i from an evaluated element, and adds the values
+// of variables to its label.
+func (d *diagram) evaluated(i int, value RuleElement, err error) {
+	n := &d.nodes[i]
+	switch v := value.(type) {
+	case nil:
+		n.state = stateError
+		if err != nil {
+			n.label += "\n" + err.Error()
+		}
+	case Attribute:
+		n.state = stateFalse
+		if v.getValue() {
+			n.state = stateTrue
+		}
+	case Variable:
+		if !isLiteral(n.label) {
+			n.label += fmt.Sprintf(" = %v", v.getValue())
+		}
+	}
+}
+
+func ruleDiagram(r Rule, opts []DiagramOption) (*diagram, error) {
+	var cfg diagramConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	rr, ok := r.(*rule)
+	if !ok {
+		return nil, fmt.Errorf("%w: rule %s cannot be drawn", ErrInvalidRule, r.Name())
+	}
+
+	root := rr.tree()
+	var values map[*node]nodeValue
+	if cfg.ctx != nil {
+		values = make(map[*node]nodeValue)
+		ev := newEvaluation(cfg.ctx)
+		if rr.references != nil {
+			ev.addResolver(rr.references)
+		}
+		rr.evaluateNode(ev, root, values)
+	}
+
+	d := &diagram{title: rr.name}
+	d.addTree(root, values)
+	return d, nil
+}
+
+// nodeValue is the result of a node of an expression tree in an evaluation.
+type nodeValue struct {
+	value RuleElement
+	err   error
+}
+
+// evaluateNode evaluates n and its descendants, recording their results in values.
+// Errors are recorded only for the nodes they occur in, and nodes with operands
+// that cannot be evaluated are recorded without a value.
+func (r *rule) evaluateNode(ev *evaluation, n *node, values map[*node]nodeValue) RuleElement {
+	args := make([]RuleElement, len(n.children))
+	failed := false
+	for i, child := range n.children {
+		args[i] = r.evaluateNode(ev, child, values)
+		failed = failed || args[i] == nil
+	}
+	if failed {
+		values[n] = nodeValue{}
+		return nil
+	}
+	value, err := r.apply(ev, n.token, args)
+	values[n] = nodeValue{value: value, err: err}
+	return value
+}
+
+// addTree adds n and its descendants to the diagram.
+func (d *diagram) addTree(n *node, values map[*node]nodeValue) {
+	var i int
+	var children []*node
+	switch {
+	case n.token == kAND || n.token == kOR:
+		i = d.add(n.token, shapeOperator)
+		children = flatten(n, n.token, nil)
+	case n.token == kNOT || n.token == kXOR:
+		i = d.add(n.token, shapeOperator)
+		children = n.children
+	default:
+		i = d.add(n.String(), shapeCondition)
+	}
+	if v, ok := values[n]; ok {
+		if len(children) == 0 {
+			// Conditions are drawn as leaves, so they show the errors of their operands.
+			n.walk(func(operand *node) {
+				if v.err == nil {
+					v.err = values[operand].err
+				}
+			})
+		}
+		d.evaluated(i, v.value, v.err)
+	}
+	for _, child := range children {
+		d.edges = append(d.edges, [2]int{i, len(d.nodes)})
+		d.addTree(child, values)
+	}
+}
+
+func (g *DependencyGraph) diagram(opts []DiagramOption) *diagram {
+	var cfg diagramConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	var ev *evaluation
+	if cfg.ctx != nil {
+		ev = newEvaluation(cfg.ctx)
+		for _, resolver := range g.resolvers {
+			ev.addResolver(resolver)
+		}
+	}
+
+	d := &diagram{}
+	ids := make(map[string]int)
+	for _, name := range g.rules {
+		ids[name] = d.add(name, shapeRule)
+		if ev != nil {
+			value, err := ev.evaluateRule(g.byName[name])
+			d.evaluated(ids[name], value, err)
+		}
+	}
+	for _, name := range g.Elements() {
+		i := d.add(name, shapeElement)
+		ids[name] = i
+		if ev != nil {
+			if value, err := ev.resolve(name); err == nil {
+				d.evaluated(i, value, nil)
+			} else {
+				d.nodes[i].state = stateError
+			}
+		}
+	}
+	for _, name := range g.rules {
+		for _, ref := range g.references[name] {
+			d.edges = append(d.edges, [2]int{ids[name], ids[ref]})
+		}
+		for _, e := range g.elements[name] {
+			d.edges = append(d.edges, [2]int{ids[name], ids[e]})
+		}
+	}
+	return d
+}
+
+func (d *diagram) dot() string {
+	var b strings.Builder
+	if d.title != "" {
+		fmt.Fprintf(&b, "digraph %s {\n", dotString(d.title))
+	} else {
+		b.WriteString("digraph {\n")
+	}
+	b.WriteString("  node [fontname=\"Helvetica\"];\n")
+	for i, n := range d.nodes {
+		attrs := []string{"label=" + dotString(n.label)}
+		var style []string
+		switch n.shape {
+		case shapeOperator:
+			attrs = append(attrs, "shape=circle")
+		case shapeCondition, shapeRule:
+			attrs = append(attrs, "shape=box")
+			style = append(style, "rounded")
+		case shapeElement:
+			attrs = append(attrs, "shape=ellipse")
+		}
+		if colors, ok := diagramColors[n.state]; ok {
+			style = append(style, "filled")
+			attrs = append(attrs, "fillcolor="+dotString(colors[0]), "color="+dotString(colors[1]))
+		}
+		if len(style) > 0 {
+			attrs = append(attrs, "style="+dotString(strings.Join(style, ",")))
+		}
+		fmt.Fprintf(&b, "  n%d [%s];\n", i, strings.Join(attrs, ", "))
+	}
+	for _, e := range d.edges {
+		fmt.Fprintf(&b, "  n%d -> n%d;\n", e[0], e[1])
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// dotString quotes s as a DOT string.
+func dotString(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	s = strings.ReplaceAll(s, "\n", `\n`)
+	return `"` + s + `"`
+}
+
+func (d *diagram) mermaid() string {
+	var b strings.Builder
+	if d.title != "" {
+		// The title is a YAML string, quoted so names may hold ':' or '#'.
+		fmt.Fprintf(&b, "---\ntitle: %s\n---\n", strconv.Quote(d.title))
+	}
+	b.WriteString("flowchart TD\n")
+	for i, n := range d.nodes {
+		label := mermaidString(n.label)
+		switch n.shape {
+		case shapeOperator:
+			fmt.Fprintf(&b, "  n%d((\"%s\"))\n", i, label)
+		case shapeCondition, shapeRule:
+			fmt.Fprintf(&b, "  n%d(\"%s\")\n", i, label)
+		case shapeElement:
+			fmt.Fprintf(&b, "  n%d([\"%s\"])\n", i, label)
+		}
+	}
+	for _, e := range d.edges {
+		fmt.Fprintf(&b, "  n%d --> n%d\n", e[0], e[1])
+	}
+
+	classes := map[diagramState]string{stateTrue: "true", stateFalse: "false", stateError: "error"}
+	for _, state := range []diagramState{stateTrue, stateFalse, stateError} {
+		var ids []string
+		for i, n := range d.nodes {
+			if n.state == state {
+				ids = append(ids, fmt.Sprintf("n%d", i))
+			}
+		}
+		if len(ids) == 0 {
+			continue
+		}
+		colors := diagramColors[state]
+		fmt.Fprintf(&b, "  classDef %s fill:%s,stroke:%s\n", classes[state], colors[0], colors[1])
+		fmt.Fprintf(&b, "  class %s %s\n", strings.Join(ids, ","), classes[state])
+	}
+	return b.String()
+}
+
+// mermaidString escapes s for a quoted Mermaid label.
+func mermaidString(s string) string {
+	s = strings.ReplaceAll(s, `"`, "#quot;")
+	return strings.ReplaceAll(s, "\n", "<br/>")
+}
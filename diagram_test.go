@@ -0,0 +1,127 @@
+package rules
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestToDOT(t *testing.T) {
+	r := MustParse("upgrade", `gold AND NOT blocked AND (age GT 18 OR LOWER(country) EQ "pl")`)
+	got, err := ToDOT(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `digraph "upgrade" {
+  node [fontname="Helvetica"];
+  n0 [label="AND", shape=circle];
+  n1 [label="gold", shape=box, style="rounded"];
+  n2 [label="NOT", shape=circle];
+  n3 [label="blocked", shape=box, style="rounded"];
+  n4 [label="OR", shape=circle];
+  n5 [label="age GT 18", shape=box, style="rounded"];
+  n6 [label="LOWER(country) EQ \"pl\"", shape=box, style="rounded"];
+  n0 -> n1;
+  n0 -> n2;
+  n2 -> n3;
+  n0 -> n4;
+  n4 -> n5;
+  n4 -> n6;
+}
+`
+	if got != want {
+		t.Errorf("expected:\n%s\ngot:\n%s", want, got)
+	}
+
+	if _, err := ToDOT(constantRule(true)); !errors.Is(err, ErrInvalidRule) {
+		t.Errorf("expected ErrInvalidRule, got %v", err)
+	}
+}
+
+func TestToMermaid(t *testing.T) {
+	r := MustParse("upgrade", `gold AND NOT blocked AND (age GT 18 OR LOWER(country) EQ "pl")`)
+	ctx := NewContext(
+		NewVariable[int]("age")(30),
+		NewAttribute("gold")(true),
+		NewAttribute("blocked")(false),
+	)
+	got, err := ToMermaid(r, WithEvaluation(ctx))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `---
+title: "upgrade"
+---
+flowchart TD
+  n0(("AND"))
+  n1("gold")
+  n2(("NOT"))
+  n3("blocked")
+  n4(("OR"))
+  n5("age GT 18")
+  n6("LOWER(country) EQ #quot;pl#quot;<br/>missing data in context: country")
+  n0 --> n1
+  n0 --> n2
+  n2 --> n3
+  n0 --> n4
+  n4 --> n5
+  n4 --> n6
+  classDef true fill:#c8e6c9,stroke:#2e7d32
+  class n1,n2,n5 true
+  classDef false fill:#ffcdd2,stroke:#c62828
+  class n3 false
+  classDef error fill:#eeeeee,stroke:#9e9e9e
+  class n0,n4,n6 error
+`
+	if got != want {
+		t.Errorf("expected:\n%s\ngot:\n%s", want, got)
+	}
+}
+
+func TestToMermaidTitle(t *testing.T) {
+	got, err := ToMermaid(MustParse(`fare: band "#1"`, "gold"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "---\ntitle: " + `"fare: band \"#1\""` + "\n---\n"; !strings.HasPrefix(got, want) {
+		t.Errorf("expected a quoted title:\n%s\ngot:\n%s", want, got)
+	}
+}
+
+func TestDependencyGraphDiagrams(t *testing.T) {
+	set := NewRuleSet(
+		MustParse("eligible", "NOT blocked"),
+		MustParse("upgrade", "eligible AND age GT 18"),
+	)
+	g := NewDependencyGraph(set)
+	ctx := NewContext(
+		NewVariable[int]("age")(30),
+		NewAttribute("blocked")(true),
+	)
+
+	got := g.DOT(WithEvaluation(ctx))
+	want := `digraph {
+  node [fontname="Helvetica"];
+  n0 [label="eligible", shape=box, fillcolor="#ffcdd2", color="#c62828", style="rounded,filled"];
+  n1 [label="upgrade", shape=box, fillcolor="#ffcdd2", color="#c62828", style="rounded,filled"];
+  n2 [label="age = 30", shape=ellipse];
+  n3 [label="blocked", shape=ellipse, fillcolor="#c8e6c9", color="#2e7d32", style="filled"];
+  n0 -> n3;
+  n1 -> n0;
+  n1 -> n2;
+}
+`
+	if got != want {
+		t.Errorf("expected:\n%s\ngot:\n%s", want, got)
+	}
+
+	got = g.Mermaid()
+	for _, line := range []string{`n0("eligible")`, `n2(["age"])`, "n1 --> n0", "n1 --> n2"} {
+		if !strings.Contains(got, "  "+line+"\n") {
+			t.Errorf("expected %q in:\n%s", line, got)
+		}
+	}
+	if strings.Contains(got, "classDef") {
+		t.Errorf("expected no colours without an evaluation, got:\n%s", got)
+	}
+}
@@ -0,0 +1,197 @@
+package rules
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// defaultMaxIterations is the number of firings after which an Engine gives up,
+// unless configured otherwise.
+const defaultMaxIterations = 1000
+
+// Production pairs a rule with the actions executed when the rule holds.
+type Production struct {
+	// Rule is the condition of the production.
+	Rule Rule
+	// Actions are executed in order when the production fires.
+	Actions []Action
+	// Salience orders the productions whose rules hold at the same time:
+	// the one with the highest salience fires first.
+	Salience int
+}
+
+// Engine is a forward chaining production rule engine. It repeatedly evaluates
+// the rules of its productions against a working memory, and fires the actions
+// of the most salient production that holds. Actions may change the working
+// memory, which makes other productions hold, until no production is left to
+// fire or the iteration limit is reached.
+//
+// A production does not fire again until an element its rule depends on changes.
+type Engine struct {
+	productions   []Production
+	index         map[string]int
+	maxIterations int
+}
+
+// EngineOption configures an Engine.
+type EngineOption func(*Engine)
+
+// WithMaxIterations sets the maximum number of firings of a single run.
+func WithMaxIterations(n int) EngineOption {
+	return func(e *Engine) {
+		e.maxIterations = n
+	}
+}
+
+// NewEngine creates an engine without productions.
+func NewEngine(opts ...EngineOption) *Engine {
+	e := &Engine{
+		index:         make(map[string]int),
+		maxIterations: defaultMaxIterations,
+	}
+	for _, opt := range opts {
+		opt(e)
+	}
+	return e
+}
+
+// Add adds a production to the engine. Rule names must be unique.
+func (e *Engine) Add(p Production) error {
+	if _, ok := e.index[p.Rule.Name()]; ok {
+		return fmt.Errorf("%w: %s", ErrDuplicateRule, p.Rule.Name())
+	}
+	if err := checkCycles(p.Rule, e.lookupRule); err != nil {
+		return err
+	}
+	e.index[p.Rule.Name()] = len(e.productions)
+	e.productions = append(e.productions, p)
+	return nil
+}
+
+func (e *Engine) lookupRule(name string) (Rule, bool) {
+	i, ok := e.index[name]
+	if !ok {
+		return nil, false
+	}
+	return e.productions[i].Rule, true
+}
+
+// RunResult describes a run of an Engine.
+type RunResult struct {
+	// Fired holds the names of the fired productions, in firing order.
+	Fired []string
+	// Halted is true if an action stopped the engine.
+	Halted bool
+	// Waiting holds the productions whose rules could not be evaluated when the run
+	// ended because of missing data, with the names of the elements they miss. No
+	// production asserted those elements, so they may be misspelled.
+	Waiting map[string][]string
+}
+
+// run is the state of a single run of an engine.
+type run struct {
+	wm     *WorkingMemory
+	halted bool
+}
+
+// Run fires productions against wm until none is left to fire. It fails with
+// ErrMaxIterations if productions keep firing past the iteration limit.
+func (e *Engine) Run(wm *WorkingMemory) (*RunResult, error) {
+	r := &run{wm: wm}
+	result := &RunResult{}
+
+	// firedAt holds the working memory version at which each production last fired.
+	firedAt := make(map[int]int)
+
+	for {
+		agenda, waiting, err := e.agenda(wm, firedAt)
+		if err != nil {
+			return result, err
+		}
+		if len(agenda) == 0 {
+			result.Waiting = waiting
+			return result, nil
+		}
+		if len(result.Fired) >= e.maxIterations {
+			return result, fmt.Errorf("%w: %d", ErrMaxIterations, e.maxIterations)
+		}
+
+		i := agenda[0]
+		p := e.productions[i]
+		firedAt[i] = wm.version
+		result.Fired = append(result.Fired, p.Rule.Name())
+
+		if err := actions(p.Actions).execute(r); err != nil {
+			return result, fmt.Errorf("firing %s: %w", p.Rule.Name(), err)
+		}
+		if r.halted {
+			result.Halted = true
+			return result, nil
+		}
+	}
+}
+
+// agenda returns the indexes of the productions ready to fire, most salient first,
+// and the productions waiting for missing elements.
+func (e *Engine) agenda(wm *WorkingMemory, firedAt map[int]int) ([]int, map[string][]string, error) {
+	ev := newEvaluation(wm)
+	ev.addResolver(e)
+
+	var agenda []int
+	var waiting map[string][]string
+	for i, p := range e.productions {
+		if version, ok := firedAt[i]; ok && !wm.changedSince(version, identifiers(p.Rule)) {
+			continue
+		}
+		result, err := ev.evaluateRule(p.Rule)
+		if errors.Is(err, ErrMissingDataInContext) {
+			// Facts may be asserted later by other productions.
+			if waiting == nil {
+				waiting = make(map[string][]string)
+			}
+			waiting[p.Rule.Name()] = e.missing(p.Rule, wm)
+			continue
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		if result.getValue() {
+			agenda = append(agenda, i)
+		}
+	}
+
+	sort.SliceStable(agenda, func(i, j int) bool {
+		return e.productions[agenda[i]].Salience > e.productions[agenda[j]].Salience
+	})
+	return agenda, waiting, nil
+}
+
+// missing returns the names of the elements r reads, directly or through the rules
+// it references, that are not in wm.
+func (e *Engine) missing(r Rule, wm *WorkingMemory) []string {
+	var names []string
+	seen := make(map[string]bool)
+	var visit func(r Rule)
+	visit = func(r Rule) {
+		for _, name := range identifiers(r) {
+			if seen[name] {
+				continue
+			}
+			seen[name] = true
+			if _, ok := wm.findElement(name); ok {
+				continue
+			}
+			if pr, ok := r.(*rule); ok {
+				if ref, ok := pr.lookupReference(name, []ruleResolver{e}); ok {
+					visit(ref)
+					continue
+				}
+			}
+			names = append(names, name)
+		}
+	}
+	visit(r)
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,179 @@
+package rules
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestEngine(t *testing.T) {
+	var gold = NewAttribute("gold")
+	var miles = NewVariable[int]("miles")
+
+	upgrade, err := ParseAction(`SET upgradeClass = "business"; SET lounge = TRUE`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bonus, err := ParseAction("SET miles = miles")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	engine := NewEngine()
+	productions := []Production{
+		{
+			Rule:    MustParse("loungeMeal", "lounge"),
+			Actions: []Action{ActionFunc(func(wm *WorkingMemory) error { return wm.SetValue("meal", "premium") })},
+		},
+		{
+			Rule:     MustParse("upgrade", "gold AND miles GT 10000"),
+			Actions:  []Action{upgrade},
+			Salience: 10,
+		},
+		{
+			Rule:    MustParse("bonus", `upgradeClass EQ "business"`),
+			Actions: []Action{bonus},
+		},
+	}
+	for _, p := range productions {
+		if err := engine.Add(p); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	wm := NewWorkingMemory(gold(true), miles(12000))
+	result, err := engine.Run(wm)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := []string{"upgrade", "loungeMeal", "bonus"}; !reflect.DeepEqual(result.Fired, want) {
+		t.Errorf("Fired = %v, want %v", result.Fired, want)
+	}
+	if got, want := wm.String(), "gold(true), miles(12000), upgradeClass(business), lounge(true), meal(premium)"; got != want {
+		t.Errorf("working memory = %v, want %v", got, want)
+	}
+}
+
+func TestEngineForwardChaining(t *testing.T) {
+	var count = NewVariable[int]("count")
+
+	increment := ActionFunc(func(wm *WorkingMemory) error {
+		c, err := VariableValue[int](wm, "count")
+		if err != nil {
+			return err
+		}
+		return wm.SetValue("count", c+1)
+	})
+
+	engine := NewEngine(WithMaxIterations(5))
+	if err := engine.Add(Production{Rule: MustParse("countUp", "count LT 3"), Actions: []Action{increment}}); err != nil {
+		t.Fatal(err)
+	}
+
+	wm := NewWorkingMemory(count(0))
+	result, err := engine.Run(wm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Fired) != 3 {
+		t.Errorf("Fired = %v, want 3 firings", result.Fired)
+	}
+	if c, _ := VariableValue[int](wm, "count"); c != 3 {
+		t.Errorf("count = %d, want 3", c)
+	}
+
+	_, err = engine.Run(NewWorkingMemory(count(-10)))
+	if !errors.Is(err, ErrMaxIterations) {
+		t.Errorf("Run() error = %v, want %v", err, ErrMaxIterations)
+	}
+}
+
+func TestEngineHalt(t *testing.T) {
+	var a = NewAttribute("a")
+
+	engine := NewEngine()
+	stop, err := ParseAction("SET b = TRUE\nHALT")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := engine.Add(Production{Rule: MustParse("first", "a"), Actions: []Action{stop}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := engine.Add(Production{Rule: MustParse("second", "b")}); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := engine.Run(NewWorkingMemory(a(true)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.Halted || !reflect.DeepEqual(result.Fired, []string{"first"}) {
+		t.Errorf("Run() = %+v, want to halt after first", result)
+	}
+}
+
+func TestEngineWaiting(t *testing.T) {
+	var gold = NewAttribute("gold")
+
+	engine := NewEngine()
+	lounge, err := ParseAction("SET lounge = TRUE")
+	if err != nil {
+		t.Fatal(err)
+	}
+	productions := []Production{
+		{Rule: MustParse("upgrade", "gold"), Actions: []Action{lounge}},
+		// loungeAccess is a typo of lounge, which no production asserts.
+		{Rule: MustParse("meal", "loungeAccess AND gold")},
+		{Rule: MustParse("drinks", "meal AND lounge")},
+	}
+	for _, p := range productions {
+		if err := engine.Add(p); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	result, err := engine.Run(NewWorkingMemory(gold(true)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string][]string{"meal": {"loungeAccess"}, "drinks": {"loungeAccess"}}
+	if !reflect.DeepEqual(result.Waiting, want) {
+		t.Errorf("Waiting = %v, want %v", result.Waiting, want)
+	}
+}
+
+func TestParseActionQuotedSemicolons(t *testing.T) {
+	var a = NewAttribute("a")
+
+	set, err := ParseAction(`SET msg = "a;b"; SET note = "say \"hi;\""`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := len(set.(actions)); n != 2 {
+		t.Fatalf("ParseAction() = %d actions, want 2", n)
+	}
+
+	engine := NewEngine()
+	if err := engine.Add(Production{Rule: MustParse("first", "a"), Actions: []Action{set}}); err != nil {
+		t.Fatal(err)
+	}
+	wm := NewWorkingMemory(a(true))
+	if _, err := engine.Run(wm); err != nil {
+		t.Fatal(err)
+	}
+	if msg, _ := VariableValue[string](wm, "msg"); msg != "a;b" {
+		t.Errorf("msg = %q, want %q", msg, "a;b")
+	}
+	if note, _ := VariableValue[string](wm, "note"); note != `say "hi;"` {
+		t.Errorf("note = %q, want %q", note, `say "hi;"`)
+	}
+}
+
+func TestParseActionErrors(t *testing.T) {
+	for _, src := range []string{"", "JUMP x", "SET = 5", "SET x 5", "SET x = (", "HALT now"} {
+		if _, err := ParseAction(src); err == nil {
+			t.Errorf("ParseAction(%q) expected error", src)
+		}
+	}
+}
@@ -51,6 +51,10 @@ func TestEvaluate(t *testing.T) {
 			rule: "A AND B AND C GT D",
 			ctx:  NewContext(A(true), B(true), C("D"), D("C")),
 		},
+		{
+			rule: "A AND NOT (C LT D)",
+			ctx:  NewContext(A(true), C("D"), D("D")),
+		},
 		{
 			rule: "A AND B AND C EQ D AND C EQ D",
 			ctx:  NewContext(A(true), B(true), C("D"), D("D")),
@@ -0,0 +1,117 @@
+package rules
+
+import (
+	"fmt"
+)
+
+// evaluation is a rule context that carries the state of a single evaluation.
+// Values of computed elements and results of referenced rules are cached apart, as
+// a rule may share its name with an element, and the ones being computed are tracked
+// so cycles between them are reported instead of recursing forever.
+type evaluation struct {
+	RuleContext
+
+	computed  map[string]RuleElement
+	results   map[string]Attribute
+	computing map[string]bool
+	resolving map[string]bool
+	resolvers []ruleResolver
+}
+
+// newEvaluation wraps ctx in an evaluation, unless ctx already is one, so nested
+// evaluations started from computed elements share the same cache.
+func newEvaluation(ctx RuleContext) *evaluation {
+	if e, ok := ctx.(*evaluation); ok {
+		return e
+	}
+	return &evaluation{
+		RuleContext: ctx,
+		computed:    make(map[string]RuleElement),
+		results:     make(map[string]Attribute),
+		computing:   make(map[string]bool),
+		resolving:   make(map[string]bool),
+	}
+}
+
+func (e *evaluation) String() string {
+	return fmt.Sprint(e.RuleContext)
+}
+
+// resolve returns the element called name. Elements of the context take precedence
+// over rules of the same name.
+func (e *evaluation) resolve(name string) (RuleElement, error) {
+	el, ok := e.findElement(name)
+	if !ok {
+		return e.resolveRule(name)
+	}
+
+	c, ok := el.(computedElement)
+	if !ok {
+		return el, nil
+	}
+	if value, ok := e.computed[name]; ok {
+		return value, nil
+	}
+	if e.computing[name] {
+		return nil, fmt.Errorf("%w: %s", ErrCyclicDependency, name)
+	}
+
+	e.computing[name] = true
+	defer delete(e.computing, name)
+
+	value, err := c.compute(e)
+	if err != nil {
+		return nil, fmt.Errorf("computing %s: %w", name, err)
+	}
+	e.computed[name] = value
+
+	return value, nil
+}
+
+func (e *evaluation) addResolver(resolver ruleResolver) {
+	for _, r := range e.resolvers {
+		if r == resolver {
+			return
+		}
+	}
+	e.resolvers = append(e.resolvers, resolver)
+}
+
+// resolveRule evaluates the rule referenced by name and returns its result as an attribute.
+func (e *evaluation) resolveRule(name string) (RuleElement, error) {
+	var ref Rule
+	for _, resolver := range e.resolvers {
+		if r, ok := resolver.lookupRule(name); ok {
+			ref = r
+			break
+		}
+	}
+	if ref == nil {
+		return nil, fmt.Errorf("%w: %s", ErrMissingDataInContext, name)
+	}
+
+	return e.evaluateRule(ref)
+}
+
+// evaluateRule evaluates ref, or returns its cached result if it was already evaluated.
+func (e *evaluation) evaluateRule(ref Rule) (Attribute, error) {
+	name := ref.Name()
+	if a, ok := e.results[name]; ok {
+		return a, nil
+	}
+	if e.resolving[name] {
+		return nil, fmt.Errorf("%w: %s", ErrCyclicDependency, name)
+	}
+
+	e.resolving[name] = true
+	defer delete(e.resolving, name)
+
+	value, err := ref.Evaluate(e)
+	if err != nil {
+		return nil, fmt.Errorf("evaluating %s: %w", name, err)
+	}
+	result := attribute{name: name, value: value}
+	e.results[name] = result
+
+	return result, nil
+}
@@ -101,3 +101,54 @@ func ExampleRuleSet() {
 
 	// Output: true
 }
+
+func ExampleRuleRegistry() {
+	registry, err := rules.NewRuleRegistry(suitableForUpgrade)
+	if err != nil {
+		panic(err)
+	}
+
+	upgradeAndBaggage := rules.MustParse(
+		"upgradeAndBaggage",
+		`suitableForUpgrade AND passengerIsGoldCardHolder`,
+		rules.WithRules(registry),
+	)
+
+	passengerContext := rules.NewContext(
+		isPassengerEconomy(true),
+		isPassengerGoldCardHolder(true),
+		isPassengerSilverCardHolder(false),
+		isPassengerDressSmart(true),
+		baggageWeight(4.6),
+		baggageAllowance(7),
+	)
+
+	result, err := upgradeAndBaggage.Evaluate(passengerContext)
+	if err != nil {
+		panic(err)
+	}
+
+	fmt.Println(result)
+
+	// Output: true
+}
+
+func ExampleRuleSet_EvaluateAll() {
+	ruleSet := rules.NewRuleSet(suitableForUpgrade, canHaveAdditionalBaggage)
+
+	passengerContext := rules.NewContext(
+		isPassengerEconomy(true),
+		isPassengerGoldCardHolder(false),
+		isPassengerSilverCardHolder(true),
+		isPassengerDressSmart(true),
+		baggageWeight(4.6),
+		baggageAllowance(7),
+	)
+
+	result := ruleSet.EvaluateAll(passengerContext)
+	for _, failed := range result.Failed() {
+		fmt.Println("unmet:", failed.Name)
+	}
+
+	// Output: unmet: canHaveAdditionalBaggage
+}
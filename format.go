@@ -0,0 +1,96 @@
+package rules
+
+import (
+	"fmt"
+	"strings"
+)
+
+const formatIndent = "    "
+
+// Format returns the expression of a rule formatted over multiple lines, along
+// with the comments written in its source. Each top-level clause is printed on
+// its own line, starting with its operator:
+//
+//	// EU 261/2004, art. 5
+//	passengerIsEconomy
+//	AND (passengerIsGoldCardHolder OR passengerIsSilverCardHolder)
+//
+// Parenthesised clauses containing comments are printed the same way, indented.
+// The result parses back to the same rule.
+func Format(r Rule) string {
+	pr, ok := r.(*rule)
+	if !ok {
+		return fmt.Sprint(r)
+	}
+
+	f := formatter{printed: map[*node]bool{}}
+	f.block(pr.tree(), "")
+	for _, c := range pr.comments[len(pr.r)] {
+		f.comment(c, "")
+	}
+
+	return strings.TrimSuffix(f.b.String(), "\n")
+}
+
+type formatter struct {
+	b       strings.Builder
+	printed map[*node]bool
+}
+
+// block prints n with each clause of its top-level chain of AND, OR and XOR on its own line.
+func (f *formatter) block(n *node, indent string) {
+	var clauses []*node
+	for n.isLogical() && !n.needsParens(0) {
+		clauses = append(clauses, n)
+		n = n.children[0]
+	}
+	if n.isLogical() {
+		clauses = append(clauses, n)
+		f.line("", n.children[0], true, indent)
+	} else {
+		f.line("", n, false, indent)
+	}
+	for i := len(clauses) - 1; i >= 0; i-- {
+		op := clauses[i]
+		f.comments(op, indent)
+		f.line(op.token+" ", op.children[1], op.needsParens(1), indent)
+	}
+}
+
+// line prints a single clause, prefixed with its operator.
+func (f *formatter) line(prefix string, n *node, parens bool, indent string) {
+	block := parens && n.isLogical() && n.hasComments(f.printed)
+	if !block {
+		f.comments(n.start(), indent)
+	}
+	f.b.WriteString(indent + prefix)
+
+	switch {
+	case block:
+		f.b.WriteString("(\n")
+		f.block(n, indent+formatIndent)
+		f.b.WriteString(indent + ")")
+	case parens:
+		f.b.WriteString("(")
+		n.write(&f.b, f.printed)
+		f.b.WriteString(")")
+	default:
+		n.write(&f.b, f.printed)
+	}
+	f.b.WriteString("\n")
+}
+
+// comments prints the comments of n on their own lines.
+func (f *formatter) comments(n *node, indent string) {
+	if f.printed[n] {
+		return
+	}
+	f.printed[n] = true
+	for _, c := range n.comments {
+		f.comment(c, indent)
+	}
+}
+
+func (f *formatter) comment(c, indent string) {
+	f.b.WriteString(indent + c + "\n")
+}
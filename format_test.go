@@ -0,0 +1,92 @@
+package rules
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		name string
+		expr string
+		want string
+	}{
+		{
+			name: "no comments",
+			expr: "A AND (B OR C) AND D LTE E",
+			want: "A\nAND (B OR C)\nAND D LTE E",
+		},
+		{
+			name: "grouped first clause",
+			expr: "(A OR B) AND C",
+			want: "(A OR B)\nAND C",
+		},
+		{
+			name: "clause comments",
+			expr: `// EU 261/2004, art. 5
+				A
+				// GDPR
+				AND (B OR C)
+				AND /* reg. 3 */ D LTE E // trailing`,
+			want: "// EU 261/2004, art. 5\nA\n// GDPR\nAND (B OR C)\n/* reg. 3 */\nAND D LTE E\n// trailing",
+		},
+		{
+			name: "nested comments",
+			expr: `A AND (
+				// gold
+				B
+				// silver
+				OR C
+			)`,
+			want: "A\nAND (\n    // gold\n    B\n    // silver\n    OR C\n)",
+		},
+		{
+			name: "inline comments",
+			expr: `A AND NOT (B /* x */ EQ C) AND LOWER(/* y */ D) EQ "pl"`,
+			want: "A\nAND NOT (B /* x */ EQ C)\nAND LOWER(/* y */ D) EQ \"pl\"",
+		},
+		{
+			name: "line comment ending a block comment",
+			expr: "X AND NOT (A AND // c */ d\n B)",
+			want: "X\nAND NOT (A AND /* c * / d */ B)",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := Parse("rule", tt.expr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := Format(r)
+			if got != tt.want {
+				t.Errorf("Format() = %q, want %q", got, tt.want)
+			}
+
+			r2, err := Parse("rule", got)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(r.(*rule).r, r2.(*rule).r) {
+				t.Errorf("Format() changed the rule: %v, want %v", r2.(*rule).r, r.(*rule).r)
+			}
+			if Format(r2) != got {
+				t.Errorf("Format() is not stable: %q, want %q", Format(r2), got)
+			}
+		})
+	}
+}
+
+func TestComments(t *testing.T) {
+	got, err := tokenize("A // comment (\nAND /* B AND */ C / D")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"A", "AND", "C", "D"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("tokenize() = %v, want %v", got, want)
+	}
+
+	if _, err := tokenize("A /* unterminated"); err == nil {
+		t.Errorf("tokenize() expected error for unterminated comment")
+	}
+}
@@ -0,0 +1,218 @@
+package rules
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+	"time"
+)
+
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+// FunctionRegistry holds Go functions that can be called from rule expressions,
+// such as LOWER(country) EQ "pl". Built-in functions are always available;
+// a registry adds functions on top of them.
+//
+// Functions must be registered before the rules calling them are parsed.
+type FunctionRegistry struct {
+	funcs map[string]*function
+}
+
+type function struct {
+	name    string
+	fn      reflect.Value
+	params  []reflect.Type
+	result  reflect.Type
+	withErr bool
+}
+
+// NewFunctionRegistry creates an empty function registry.
+func NewFunctionRegistry() *FunctionRegistry {
+	return &FunctionRegistry{
+		funcs: make(map[string]*function),
+	}
+}
+
+// Register adds a typed Go function to the registry under the given name.
+// The function must take booleans, numbers or strings and return a single
+// boolean, number or string, optionally followed by an error.
+// Functions returning a boolean can be used as attributes, others as variables.
+func (f *FunctionRegistry) Register(name string, fn any) error {
+	if name == "" || isOperator(name) {
+		return fmt.Errorf("%w: invalid function name %q", ErrInvalidFunction, name)
+	}
+	if _, ok := f.lookup(name); ok {
+		return fmt.Errorf("%w: function %s is already registered", ErrInvalidFunction, name)
+	}
+
+	fun, err := newFunction(name, fn)
+	if err != nil {
+		return err
+	}
+	f.funcs[name] = fun
+
+	return nil
+}
+
+func (f *FunctionRegistry) lookup(name string) (*function, bool) {
+	if f != nil {
+		if fun, ok := f.funcs[name]; ok {
+			return fun, true
+		}
+	}
+	fun, ok := builtins[name]
+	return fun, ok
+}
+
+func newFunction(name string, fn any) (*function, error) {
+	v := reflect.ValueOf(fn)
+	t := v.Type()
+	if t.Kind() != reflect.Func || t.IsVariadic() {
+		return nil, fmt.Errorf("%w: %s must be a non-variadic function, got %T", ErrInvalidFunction, name, fn)
+	}
+
+	fun := &function{name: name, fn: v}
+	for i := 0; i < t.NumIn(); i++ {
+		if !isSupportedType(t.In(i)) {
+			return nil, fmt.Errorf("%w: %s has unsupported parameter type %s", ErrInvalidFunction, name, t.In(i))
+		}
+		fun.params = append(fun.params, t.In(i))
+	}
+
+	switch {
+	case t.NumOut() == 2 && t.Out(1) == errorType:
+		fun.withErr = true
+	case t.NumOut() != 1:
+		return nil, fmt.Errorf("%w: %s must return a single value and an optional error", ErrInvalidFunction, name)
+	}
+	if !isSupportedType(t.Out(0)) {
+		return nil, fmt.Errorf("%w: %s has unsupported result type %s", ErrInvalidFunction, name, t.Out(0))
+	}
+	fun.result = t.Out(0)
+
+	return fun, nil
+}
+
+func isSupportedType(t reflect.Type) bool {
+	return t.Kind() == reflect.Bool || t.Kind() == reflect.String || isNumber(t.Kind())
+}
+
+// accepts reports whether an argument of the given kind can be passed as the i-th parameter.
+func (f *function) accepts(i int, k valueKind) bool {
+	return k == kindUnknown || k == kindOf(f.params[i])
+}
+
+func (f *function) call(args []RuleElement) (RuleElement, error) {
+	in := make([]reflect.Value, len(args))
+	names := make([]string, len(args))
+	for i, arg := range args {
+		names[i] = arg.getName()
+
+		var value any
+		switch a := arg.(type) {
+		case Attribute:
+			value = a.getValue()
+		case Variable:
+			value = a.getValue()
+		}
+
+		v := reflect.ValueOf(value)
+		if !v.IsValid() || kindOf(v.Type()) != kindOf(f.params[i]) || !v.CanConvert(f.params[i]) {
+			return nil, fmt.Errorf("%w: argument %d of %s must be %s, got %T", ErrInvalidRule, i+1, f.name, f.params[i], value)
+		}
+		in[i] = v.Convert(f.params[i])
+	}
+
+	out := f.fn.Call(in)
+	if f.withErr && !out[1].IsNil() {
+		return nil, fmt.Errorf("%s: %w", f.name, out[1].Interface().(error))
+	}
+
+	name := f.name + "(" + strings.Join(names, ", ") + ")"
+	if f.result.Kind() == reflect.Bool {
+		return attribute{name: name, value: out[0].Bool()}, nil
+	}
+	return literal{name: name, value: out[0].Interface()}, nil
+}
+
+// valueKind is the static kind of a value in an expression, used to type check
+// function calls at parse time.
+type valueKind int
+
+const (
+	kindUnknown valueKind = iota
+	kindBool
+	kindNumber
+	kindString
+)
+
+func kindOf(t reflect.Type) valueKind {
+	switch {
+	case t.Kind() == reflect.Bool:
+		return kindBool
+	case t.Kind() == reflect.String:
+		return kindString
+	case isNumber(t.Kind()):
+		return kindNumber
+	default:
+		return kindUnknown
+	}
+}
+
+func (k valueKind) String() string {
+	switch k {
+	case kindBool:
+		return "boolean"
+	case kindNumber:
+		return "number"
+	case kindString:
+		return "string"
+	default:
+		return "unknown"
+	}
+}
+
+var builtins = map[string]*function{}
+
+func mustBuiltin(name string, fn any) {
+	fun, err := newFunction(name, fn)
+	if err != nil {
+		panic(err)
+	}
+	builtins[name] = fun
+}
+
+func init() {
+	mustBuiltin("LOWER", strings.ToLower)
+	mustBuiltin("UPPER", strings.ToUpper)
+	mustBuiltin("TRIM", strings.TrimSpace)
+	mustBuiltin("ABS", math.Abs)
+	mustBuiltin("LEN", func(s string) int { return len([]rune(s)) })
+	mustBuiltin("DAYS_BETWEEN", daysBetween)
+}
+
+var dateLayouts = []string{time.RFC3339, "2006-01-02"}
+
+// daysBetween returns the number of whole days from a to b.
+// Both dates must be formatted as RFC 3339 timestamps or as YYYY-MM-DD.
+func daysBetween(a, b string) (int, error) {
+	from, err := parseDate(a)
+	if err != nil {
+		return 0, err
+	}
+	to, err := parseDate(b)
+	if err != nil {
+		return 0, err
+	}
+	return int(to.Sub(from).Hours() / 24), nil
+}
+
+func parseDate(s string) (time.Time, error) {
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid date %q", s)
+}
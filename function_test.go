@@ -0,0 +1,126 @@
+package rules
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestFunctionCalls(t *testing.T) {
+	var country = NewVariable[string]("country")
+	var name = NewVariable[string]("name")
+	var delta = NewVariable[float64]("delta")
+	var miles = NewVariable[int]("miles")
+	var from = NewVariable[string]("from")
+	var to = NewVariable[string]("to")
+
+	ctx := NewContext(
+		country("PL"),
+		name("Jan"),
+		delta(-3.5),
+		miles(12000),
+		from("2024-01-01"),
+		to("2024-01-31"),
+	)
+
+	tests := []struct {
+		rule string
+		want bool
+	}{
+		{rule: `LOWER(country) EQ "pl"`, want: true},
+		{rule: `UPPER(LOWER(country)) NEQ "PL"`, want: false},
+		{rule: `ABS(delta) LT 5`, want: true},
+		{rule: `ABS(delta) GT 3.5`, want: false},
+		{rule: `LEN(name) GT 0 AND miles GTE 12000`, want: true},
+		{rule: `DAYS_BETWEEN(from, to) EQ 30`, want: true},
+		{rule: `miles LT 12000`, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.rule, func(t *testing.T) {
+			r, err := Parse("rule", tt.rule)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := r.Evaluate(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("Evaluate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFunctionRegistry(t *testing.T) {
+	var code = NewVariable[string]("code")
+
+	functions := NewFunctionRegistry()
+	if err := functions.Register("IS_EU", func(code string) bool { return code == "PL" || code == "DE" }); err != nil {
+		t.Fatal(err)
+	}
+	if err := functions.Register("PREFIX", func(s string, n int) string { return s[:n] }); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := Parse("rule", `IS_EU(code) AND PREFIX(code, 1) EQ "P"`, WithFunctions(functions))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := r.Evaluate(NewContext(code("PL")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got {
+		t.Errorf("Evaluate() = %v, want true", got)
+	}
+
+	if _, err := Parse("rule", `IS_EU(code)`); !errors.Is(err, ErrUnknownFunction) {
+		t.Errorf("Parse() without registry error = %v, want %v", err, ErrUnknownFunction)
+	}
+}
+
+func TestFunctionRegistryRegister(t *testing.T) {
+	tests := []struct {
+		name string
+		fn   any
+	}{
+		{name: "LOWER", fn: strings.ToLower},
+		{name: "AND", fn: strings.ToLower},
+		{name: "NOT_A_FUNC", fn: 42},
+		{name: "VARIADIC", fn: func(s ...string) string { return "" }},
+		{name: "NO_RESULT", fn: func(s string) {}},
+		{name: "SLICE", fn: func(s []string) string { return "" }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := NewFunctionRegistry().Register(tt.name, tt.fn)
+			if !errors.Is(err, ErrInvalidFunction) {
+				t.Errorf("Register() error = %v, want %v", err, ErrInvalidFunction)
+			}
+		})
+	}
+}
+
+func TestFunctionTypeCheck(t *testing.T) {
+	tests := []struct {
+		rule string
+		err  error
+	}{
+		{rule: `LOWER(a, b) EQ "x"`, err: ErrInvalidExpression},
+		{rule: `LOWER(5) EQ "x"`, err: ErrInvalidExpression},
+		{rule: `ABS("x") EQ 5`, err: ErrInvalidExpression},
+		{rule: `ABS(LOWER(a)) EQ 5`, err: ErrInvalidExpression},
+		{rule: `MISSING(a)`, err: ErrUnknownFunction},
+		{rule: `a, b`, err: ErrInvalidExpression},
+		{rule: `LOWER((a, b)) EQ "x"`, err: ErrInvalidExpression},
+		{rule: `"unterminated`, err: ErrInvalidExpression},
+	}
+	for _, tt := range tests {
+		t.Run(tt.rule, func(t *testing.T) {
+			if _, err := Parse("rule", tt.rule); !errors.Is(err, tt.err) {
+				t.Errorf("Parse() error = %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
@@ -24,6 +24,10 @@ func FuzzParse(f *testing.F) {
 	f.Add("A AND B")
 	f.Add("A")
 	f.Add("A AND B AND (C EQ D) AND (E EQ F)")
+	f.Add(`LOWER(C) EQ "pl" AND ABS(D) LT 4.5`)
+	f.Add("DAYS_BETWEEN(C, D) GT -1")
+	f.Add("// comment\nA AND /* B */ (C OR D) // trailing")
+	f.Add("X AND NOT (A AND // c */ d\n B)")
 
 	f.Fuzz(func(t *testing.T, b string) {
 		r1, err := Parse("rule", b)
@@ -41,5 +45,13 @@ func FuzzParse(f *testing.F) {
 		if s1 != s2 {
 			t.Fatalf("%q, expected %q, got %q", b, s1, s2)
 		}
+
+		r3, err := Parse("rule", Format(r1))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if s3 := fmt.Sprint(r3); s1 != s3 {
+			t.Fatalf("%q, expected formatted %q, got %q", b, s1, s3)
+		}
 	})
 }
@@ -0,0 +1,211 @@
+// Package sat implements a small DPLL solver for boolean formulas in conjunctive
+// normal form, with helpers encoding gates into clauses (the Tseitin encoding).
+// It is meant for the formulas of rule expressions, which have at most a few
+// hundred variables.
+package sat
+
+// Literal is a variable, numbered from 1, or the negation of one.
+type Literal int
+
+// Var returns the number of the variable of l.
+func (l Literal) Var() int {
+	if l < 0 {
+		return int(-l)
+	}
+	return int(l)
+}
+
+// Not returns the negation of l.
+func (l Literal) Not() Literal {
+	return -l
+}
+
+// Solver holds a formula in conjunctive normal form: a conjunction of clauses,
+// each a disjunction of literals.
+type Solver struct {
+	vars    int
+	clauses [][]Literal
+	// empty is true once an empty clause, which cannot be satisfied, is added.
+	empty bool
+}
+
+// NewVar creates a variable and returns its positive literal.
+func (s *Solver) NewVar() Literal {
+	s.vars++
+	return Literal(s.vars)
+}
+
+// Vars returns the number of variables.
+func (s *Solver) Vars() int {
+	return s.vars
+}
+
+// AddClause adds the disjunction of lits to the formula.
+func (s *Solver) AddClause(lits ...Literal) {
+	if len(lits) == 0 {
+		s.empty = true
+		return
+	}
+	s.clauses = append(s.clauses, append([]Literal(nil), lits...))
+}
+
+// Constant returns a literal that is always value.
+func (s *Solver) Constant(value bool) Literal {
+	l := s.NewVar()
+	if value {
+		s.AddClause(l)
+	} else {
+		s.AddClause(l.Not())
+	}
+	return l
+}
+
+// And returns a literal equivalent to the conjunction of lits.
+func (s *Solver) And(lits ...Literal) Literal {
+	g := s.NewVar()
+	clause := []Literal{g}
+	for _, l := range lits {
+		s.AddClause(g.Not(), l)
+		clause = append(clause, l.Not())
+	}
+	s.AddClause(clause...)
+	return g
+}
+
+// Or returns a literal equivalent to the disjunction of lits, which is false if
+// there are none.
+func (s *Solver) Or(lits ...Literal) Literal {
+	g := s.NewVar()
+	clause := []Literal{g.Not()}
+	for _, l := range lits {
+		s.AddClause(g, l.Not())
+		clause = append(clause, l)
+	}
+	s.AddClause(clause...)
+	return g
+}
+
+// Xor returns a literal equivalent to the exclusive disjunction of a and b.
+func (s *Solver) Xor(a, b Literal) Literal {
+	g := s.NewVar()
+	s.AddClause(g.Not(), a, b)
+	s.AddClause(g.Not(), a.Not(), b.Not())
+	s.AddClause(g, a.Not(), b)
+	s.AddClause(g, a, b.Not())
+	return g
+}
+
+// ExactlyOne requires exactly one of lits to be true.
+func (s *Solver) ExactlyOne(lits ...Literal) {
+	s.AddClause(lits...)
+	for i := range lits {
+		for j := i + 1; j < len(lits); j++ {
+			s.AddClause(lits[i].Not(), lits[j].Not())
+		}
+	}
+}
+
+// Solve looks for an assignment satisfying the formula in which the assumptions
+// are true. Unlike clauses, assumptions only hold for this call. The assignment is
+// indexed by variable number, so its first element is unused.
+func (s *Solver) Solve(assumptions ...Literal) ([]bool, bool) {
+	if s.empty {
+		return nil, false
+	}
+	values := make([]int8, s.vars+1)
+	for _, l := range assumptions {
+		if value(values, l) < 0 {
+			return nil, false
+		}
+		assign(values, l)
+	}
+	if !s.dpll(values) {
+		return nil, false
+	}
+	model := make([]bool, s.vars+1)
+	for v, value := range values {
+		model[v] = value > 0
+	}
+	return model, true
+}
+
+// Satisfiable reports whether the formula can be satisfied with the assumptions true.
+func (s *Solver) Satisfiable(assumptions ...Literal) bool {
+	_, ok := s.Solve(assumptions...)
+	return ok
+}
+
+// dpll extends the partial assignment values, in which 1 is true, -1 false and 0
+// unassigned, into a satisfying assignment, if one exists.
+func (s *Solver) dpll(values []int8) bool {
+	branch, ok := s.propagate(values)
+	if !ok {
+		return false
+	}
+	if branch == 0 {
+		return true
+	}
+	for _, l := range []Literal{branch, branch.Not()} {
+		next := append([]int8(nil), values...)
+		assign(next, l)
+		if s.dpll(next) {
+			copy(values, next)
+			return true
+		}
+	}
+	return false
+}
+
+// propagate assigns the last unassigned literal of clauses whose other literals are
+// false, until there are none. It returns false on a conflict, and otherwise an
+// unassigned literal of a clause that is not yet satisfied, or 0 if every clause is.
+func (s *Solver) propagate(values []int8) (Literal, bool) {
+	for {
+		changed := false
+		var branch Literal
+		for _, clause := range s.clauses {
+			satisfied := false
+			unassigned, n := Literal(0), 0
+			for _, l := range clause {
+				switch value(values, l) {
+				case 1:
+					satisfied = true
+				case 0:
+					unassigned = l
+					n++
+				}
+				if satisfied {
+					break
+				}
+			}
+			switch {
+			case satisfied:
+			case n == 0:
+				return 0, false
+			case n == 1:
+				assign(values, unassigned)
+				changed = true
+			case branch == 0:
+				branch = unassigned
+			}
+		}
+		if !changed {
+			return branch, true
+		}
+	}
+}
+
+func value(values []int8, l Literal) int8 {
+	if l < 0 {
+		return -values[-l]
+	}
+	return values[l]
+}
+
+func assign(values []int8, l Literal) {
+	if l < 0 {
+		values[-l] = -1
+		return
+	}
+	values[l] = 1
+}
@@ -0,0 +1,138 @@
+package sat_test
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/IAmRadek/rules/internal/sat"
+)
+
+func TestSolve(t *testing.T) {
+	tests := []struct {
+		name    string
+		clauses [][]sat.Literal
+		want    bool
+	}{
+		{name: "empty formula", want: true},
+		{name: "unit", clauses: [][]sat.Literal{{1}}, want: true},
+		{name: "contradiction", clauses: [][]sat.Literal{{1}, {-1}}, want: false},
+		{name: "empty clause", clauses: [][]sat.Literal{{1}, {}}, want: false},
+		{name: "chain", clauses: [][]sat.Literal{{1}, {-1, 2}, {-2, 3}, {-3, -1, 4}}, want: true},
+		{name: "all pairs", clauses: [][]sat.Literal{{1, 2}, {1, -2}, {-1, 2}, {-1, -2}}, want: false},
+		{name: "backtracking", clauses: [][]sat.Literal{{1, 2, 3}, {-1, 2}, {-2, 3}, {-3, -1}, {-3, -2}}, want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := sat.Solver{}
+			for i := 0; i < 4; i++ {
+				s.NewVar()
+			}
+			for _, c := range tt.clauses {
+				s.AddClause(c...)
+			}
+			model, ok := s.Solve()
+			if ok != tt.want {
+				t.Fatalf("expected %t, got %t", tt.want, ok)
+			}
+			if ok && !satisfies(model, tt.clauses) {
+				t.Errorf("model %v does not satisfy %v", model, tt.clauses)
+			}
+		})
+	}
+}
+
+func TestGates(t *testing.T) {
+	for _, a := range []bool{false, true} {
+		for _, b := range []bool{false, true} {
+			s := sat.Solver{}
+			x, y := s.Constant(a), s.Constant(b)
+			and, or, xor, none := s.And(x, y), s.Or(x, y), s.Xor(x, y), s.Or()
+			model, ok := s.Solve()
+			if !ok {
+				t.Fatalf("%t, %t: expected a model", a, b)
+			}
+			get := func(l sat.Literal) bool { return model[l.Var()] == (l > 0) }
+			if get(and) != (a && b) || get(or) != (a || b) || get(xor) != (a != b) || get(none) {
+				t.Errorf("%t, %t: wrong gates in %v", a, b, model)
+			}
+		}
+	}
+
+	s := sat.Solver{}
+	lits := []sat.Literal{s.NewVar(), s.NewVar(), s.NewVar()}
+	s.ExactlyOne(lits...)
+	if s.Satisfiable(lits[0], lits[1]) || !s.Satisfiable(lits[0], lits[1].Not()) || s.Satisfiable(lits[0], lits[0].Not()) {
+		t.Errorf("wrong results with assumptions")
+	}
+	if model, ok := s.Solve(lits[2]); !ok || !model[3] {
+		t.Errorf("expected the third literal, got %v", model)
+	}
+
+	s = sat.Solver{}
+	lits = []sat.Literal{s.NewVar(), s.NewVar(), s.NewVar()}
+	s.ExactlyOne(lits...)
+	s.AddClause(lits[0].Not())
+	s.AddClause(lits[2].Not())
+	model, ok := s.Solve()
+	if !ok || model[1] || !model[2] || model[3] {
+		t.Errorf("expected only the second literal, got %v", model)
+	}
+}
+
+// TestSolveRandom compares the solver with an exhaustive search on random formulas.
+func TestSolveRandom(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	const vars = 6
+	for i := 0; i < 500; i++ {
+		clauses := make([][]sat.Literal, 1+rnd.Intn(25))
+		for j := range clauses {
+			clauses[j] = make([]sat.Literal, 1+rnd.Intn(3))
+			for k := range clauses[j] {
+				l := sat.Literal(1 + rnd.Intn(vars))
+				if rnd.Intn(2) == 0 {
+					l = l.Not()
+				}
+				clauses[j][k] = l
+			}
+		}
+
+		s := sat.Solver{}
+		for s.Vars() < vars {
+			s.NewVar()
+		}
+		for _, c := range clauses {
+			s.AddClause(c...)
+		}
+		model, ok := s.Solve()
+
+		want := false
+		for m := 0; m < 1<<vars && !want; m++ {
+			assignment := make([]bool, vars+1)
+			for v := 1; v <= vars; v++ {
+				assignment[v] = m&(1<<(v-1)) != 0
+			}
+			want = satisfies(assignment, clauses)
+		}
+		if ok != want {
+			t.Fatalf("%v: expected %t, got %t", clauses, want, ok)
+		}
+		if ok && !satisfies(model, clauses) {
+			t.Fatalf("model %v does not satisfy %v", model, clauses)
+		}
+	}
+}
+
+func satisfies(model []bool, clauses [][]sat.Literal) bool {
+	for _, c := range clauses {
+		ok := false
+		for _, l := range c {
+			if model[l.Var()] == (l > 0) {
+				ok = true
+			}
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,335 @@
+package rules
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Severity is the severity of a diagnostic.
+type Severity int
+
+const (
+	// SeverityInfo marks a matter of style, such as redundant parentheses.
+	SeverityInfo Severity = iota
+	// SeverityWarning marks an expression that is likely not what its author meant.
+	SeverityWarning
+	// SeverityError marks an expression that is always true or always false.
+	SeverityError
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityInfo:
+		return "info"
+	case SeverityWarning:
+		return "warning"
+	case SeverityError:
+		return "error"
+	default:
+		return fmt.Sprintf("Severity(%d)", int(s))
+	}
+}
+
+// Position is a position in the expression of a rule. Line and Column start at 1,
+// and columns count characters. Line is 0 when the position is unknown.
+type Position struct {
+	Offset int
+	Line   int
+	Column int
+}
+
+func (p Position) String() string {
+	if p.Line == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%d:%d", p.Line, p.Column)
+}
+
+// Diagnostic is a problem found in a rule by Lint.
+type Diagnostic struct {
+	Severity Severity
+	Pos      Position
+	// Code identifies the kind of problem, such as "double-negation".
+	Code    string
+	Message string
+}
+
+func (d Diagnostic) String() string {
+	return fmt.Sprintf("%s: %s: %s (%s)", d.Pos, d.Severity, d.Message, d.Code)
+}
+
+// Codes of the diagnostics reported by Lint.
+const (
+	lintContradiction       = "contradiction"
+	lintTautology           = "tautology"
+	lintDuplicateOperand    = "duplicate-operand"
+	lintDoubleNegation      = "double-negation"
+	lintSelfComparison      = "self-comparison"
+	lintConstantComparison  = "constant-comparison"
+	lintAmbiguousPrecedence = "ambiguous-precedence"
+	lintRedundantParens     = "redundant-parens"
+	lintRepeated            = "repeated-subexpression"
+)
+
+// negations maps comparison operators to the operators of their negations.
+var negations = map[string]string{
+	kEQ:  kNEQ,
+	kNEQ: kEQ,
+	kGT:  kLTE,
+	kLTE: kGT,
+	kLT:  kGTE,
+	kGTE: kLT,
+}
+
+// mirrors maps comparison operators to the operators comparing the same operands
+// in the reverse order.
+var mirrors = map[string]string{
+	kEQ:  kEQ,
+	kNEQ: kNEQ,
+	kGT:  kLT,
+	kLT:  kGT,
+	kGTE: kLTE,
+	kLTE: kGTE,
+}
+
+// Lint looks for mistakes in the expression of r that parse and evaluate, but
+// are likely not what its author meant: operands of AND and OR that contradict or
+// repeat each other, double negations, comparisons of an element with itself or
+// of two literals, mixes of AND, OR and XOR that rely on their equal precedence,
+// redundant parentheses and repeated sub-expressions. The diagnostics are sorted
+// by position.
+func Lint(r Rule) []Diagnostic {
+	rr, ok := r.(*rule)
+	if !ok {
+		return []Diagnostic{{
+			Severity: SeverityError,
+			Pos:      Position{Offset: -1},
+			Code:     "invalid-rule",
+			Message:  fmt.Sprintf("rule %s cannot be linted", r.Name()),
+		}}
+	}
+
+	l := &linter{rule: rr, reported: make(map[*node]bool)}
+	root := rr.tree()
+	l.lint(root, nil, 0)
+	l.repeated(root, make(map[string]bool))
+
+	sort.SliceStable(l.diagnostics, func(i, j int) bool {
+		return l.diagnostics[i].Pos.Offset < l.diagnostics[j].Pos.Offset
+	})
+	return l.diagnostics
+}
+
+type linter struct {
+	rule        *rule
+	diagnostics []Diagnostic
+	// reported holds the operands already reported as repeating or negating
+	// another operand.
+	reported map[*node]bool
+}
+
+func (l *linter) report(severity Severity, offset int, code, format string, args ...any) {
+	l.diagnostics = append(l.diagnostics, Diagnostic{
+		Severity: severity,
+		Pos:      l.position(offset),
+		Code:     code,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+// position converts a byte offset in the source of the rule into a position.
+func (l *linter) position(offset int) Position {
+	if offset < 0 || offset > len(l.rule.source) {
+		return Position{Offset: -1}
+	}
+	p := Position{Offset: offset, Line: 1, Column: 1}
+	for _, c := range l.rule.source[:offset] {
+		if c == '\n' {
+			p.Line++
+			p.Column = 1
+			continue
+		}
+		p.Column++
+	}
+	return p
+}
+
+// lint checks n, the i-th operand of parent, and its descendants.
+func (l *linter) lint(n, parent *node, i int) {
+	for j, child := range n.children {
+		l.lint(child, n, j)
+	}
+
+	l.parens(n, parent, i)
+	switch {
+	case n.token == kNOT:
+		if child := n.children[0]; child.token == kNOT {
+			l.report(SeverityWarning, n.pos, lintDoubleNegation,
+				"double negation: %s is the same as %s", n, child.children[0])
+		}
+	case isComparison(n.token):
+		l.comparison(n)
+	case n.isLogical():
+		// Operators of equal precedence associate to the left, so only the first
+		// operand can be an unparenthesised operation.
+		if child := n.children[0]; child.isLogical() && child.token != n.token && !child.grouped {
+			l.report(SeverityWarning, n.pos, lintAmbiguousPrecedence,
+				"%s, %s and %s have the same precedence, so %s is read as (%s) %s %s; add parentheses to make it explicit",
+				kAND, kOR, kXOR, n, child, n.token, parenthesised(n.children[1]))
+		}
+		if parent == nil || parent.token != n.token {
+			l.chain(n)
+		}
+	}
+}
+
+func (l *linter) comparison(n *node) {
+	left, right := n.children[0], n.children[1]
+	switch {
+	case isLiteral(left.token) && isLiteral(right.token):
+		l.report(SeverityWarning, offsetOf(n), lintConstantComparison,
+			"%s compares two literals, so its result never changes", n)
+	case left.String() == right.String():
+		always := n.token == kEQ || n.token == kGTE || n.token == kLTE
+		l.report(SeverityWarning, offsetOf(n), lintSelfComparison,
+			"%s compares %s with itself, so it is always %t", n, left, always)
+	}
+}
+
+// chain checks the operands of a chain of the same logical operator, such as
+// a AND b AND c. AND and OR are associative, so parentheses inside the chain do
+// not matter.
+func (l *linter) chain(n *node) {
+	operands := n.children
+	if n.token != kXOR {
+		operands = flatten(n, n.token, nil)
+	}
+
+	for j, b := range operands {
+		for _, a := range operands[:j] {
+			switch {
+			case a.String() == b.String():
+				if n.token == kXOR {
+					l.report(SeverityError, offsetOf(n), lintContradiction,
+						"%s is always false", n)
+				} else {
+					l.report(SeverityWarning, offsetOf(b), lintDuplicateOperand,
+						"%s appears twice in the same %s", b, n.token)
+				}
+				l.reported[b] = true
+			case complementary(a, b):
+				if n.token == kAND {
+					l.report(SeverityError, offsetOf(n), lintContradiction,
+						"%s and %s cannot both hold, so %s is always false", a, b, n)
+				} else {
+					l.report(SeverityError, offsetOf(n), lintTautology,
+						"%s or %s always holds, so %s is always true", a, b, n)
+				}
+				l.reported[b] = true
+			default:
+				continue
+			}
+			break
+		}
+	}
+}
+
+// flatten appends the operands of the chain of op rooted at n to operands.
+func flatten(n *node, op string, operands []*node) []*node {
+	for _, child := range n.children {
+		if child.token == op {
+			operands = flatten(child, op, operands)
+			continue
+		}
+		operands = append(operands, child)
+	}
+	return operands
+}
+
+// complementary reports whether a is the negation of b.
+func complementary(a, b *node) bool {
+	if a.token == kNOT {
+		return a.children[0].String() == b.String()
+	}
+	if b.token == kNOT {
+		return b.children[0].String() == a.String()
+	}
+	if !isComparison(a.token) || !isComparison(b.token) {
+		return false
+	}
+	al, ar := a.children[0].String(), a.children[1].String()
+	bl, br := b.children[0].String(), b.children[1].String()
+	return negations[a.token] == b.token && al == bl && ar == br ||
+		mirrors[negations[a.token]] == b.token && al == br && ar == bl
+}
+
+// parens checks the parentheses written around n, the i-th operand of parent.
+func (l *linter) parens(n, parent *node, i int) {
+	switch {
+	case n.parens == 0:
+		return
+	case n.parens > 1:
+		l.report(SeverityInfo, n.parenPos, lintRedundantParens, "%s is in more than one pair of parentheses", n)
+		return
+	case parent == nil:
+		l.report(SeverityInfo, n.parenPos, lintRedundantParens, "parentheses around the whole expression are redundant")
+		return
+	case !n.isBinary():
+		l.report(SeverityInfo, n.parenPos, lintRedundantParens, "parentheses around %s are redundant", n)
+		return
+	}
+
+	switch {
+	case parent.token == n.token && n.isLogical():
+		// (a AND b) AND c: the operator is associative.
+	case parent.needsParens(i):
+		return
+	case parent.isLogical() && isComparison(n.token):
+		// (age GT 18) AND gold: the parentheses help readers.
+		return
+	case parent.isLogical() && n.isLogical():
+		// (a OR b) AND c: the parentheses help readers.
+		return
+	}
+	l.report(SeverityInfo, n.parenPos, lintRedundantParens, "parentheses around %s are redundant", n)
+}
+
+// repeated reports the compound sub-expressions of n written more than once, other
+// than the duplicate operands already reported. Sub-expressions of a repeated one
+// are not reported.
+func (l *linter) repeated(n *node, seen map[string]bool) {
+	if len(n.children) == 0 || l.reported[n] {
+		return
+	}
+	s := n.String()
+	if seen[s] {
+		l.report(SeverityInfo, offsetOf(n), lintRepeated,
+			"%s is written more than once; consider a macro or a separate rule", n)
+		return
+	}
+	seen[s] = true
+	for _, child := range n.children {
+		l.repeated(child, seen)
+	}
+}
+
+// parenthesised prints n, in parentheses if it is a logical operation.
+func parenthesised(n *node) string {
+	if n.isLogical() {
+		return "(" + n.String() + ")"
+	}
+	return n.String()
+}
+
+// offsetOf returns the offset at which the expression of n starts in the source,
+// including its parentheses.
+func offsetOf(n *node) int {
+	switch {
+	case n.parens > 0:
+		return n.parenPos
+	case n.isBinary():
+		return offsetOf(n.children[0])
+	default:
+		return n.pos
+	}
+}
@@ -0,0 +1,82 @@
+package rules
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestLint(t *testing.T) {
+	defs, err := ParseDefinitions(`
+		LIMIT = 7
+		inRange(x, lo, hi) = x GTE lo AND x LTE hi
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		expr string
+		want []string
+	}{
+		{expr: "gold AND age GT 18 OR vip", want: []string{"1:20 warning ambiguous-precedence"}},
+		{expr: "(gold AND age GT 18) OR vip"},
+		{expr: "gold AND (age GT 18 OR vip)"},
+		{expr: "gold AND NOT gold", want: []string{"1:1 error contradiction"}},
+		{expr: "age GT 18 OR age LTE 18", want: []string{"1:1 error tautology"}},
+		{expr: "age GT 18 OR 18 GTE age", want: []string{"1:1 error tautology"}},
+		{expr: "age GT 18 AND age LT 18"},
+		{expr: "gold XOR gold", want: []string{"1:1 error contradiction"}},
+		{expr: "gold AND vip AND gold", want: []string{"1:18 warning duplicate-operand"}},
+		{expr: "NOT NOT gold", want: []string{"1:1 warning double-negation"}},
+		{expr: "age EQ age", want: []string{"1:1 warning self-comparison"}},
+		{expr: "1 LT 2", want: []string{"1:1 warning constant-comparison"}},
+		{expr: "(gold)", want: []string{"1:1 info redundant-parens"}},
+		{expr: "gold AND ((vip))", want: []string{"1:10 info redundant-parens"}},
+		{expr: "(gold AND vip) AND age GT 18", want: []string{"1:1 info redundant-parens"}},
+		{expr: "NOT (gold AND vip)"},
+		{expr: "(age GT 18) AND gold"},
+		{
+			expr: "(age GT 18 AND gold) OR (age GT 18 AND vip) OR\n  (age GT 18 AND gold)",
+			want: []string{"1:26 info repeated-subexpression", "2:3 warning duplicate-operand"},
+		},
+		{
+			expr: "LEN(name) GT 3 AND gold OR LEN(name) GT 3 AND vip",
+			want: []string{"1:25 warning ambiguous-precedence", "1:28 info repeated-subexpression", "1:43 warning ambiguous-precedence"},
+		},
+		{expr: "NOT (age GT 18 AND gold) OR (age GT 18 AND gold)", want: []string{"1:1 error tautology"}},
+		{expr: "inRange(age, 18, LIMIT) OR gold"},
+		{expr: "ąę EQ \"x\" AND ąę EQ \"x\"", want: []string{"1:15 warning duplicate-operand"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			var got []string
+			for _, d := range Lint(MustParse("test", tt.expr, WithDefinitions(defs))) {
+				got = append(got, fmt.Sprintf("%s %s %s", d.Pos, d.Severity, d.Code))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestLintMessages(t *testing.T) {
+	diagnostics := Lint(MustParse("test", "gold OR vip AND (age GT 18 OR age GT 18)"))
+	want := []string{
+		"1:13: warning: AND, OR and XOR have the same precedence, so gold OR vip AND (age GT 18 OR age GT 18) is read as (gold OR vip) AND (age GT 18 OR age GT 18); add parentheses to make it explicit (ambiguous-precedence)",
+		"1:31: warning: age GT 18 appears twice in the same OR (duplicate-operand)",
+	}
+	if len(diagnostics) != len(want) {
+		t.Fatalf("expected %d diagnostics, got %v", len(want), diagnostics)
+	}
+	for i, d := range diagnostics {
+		if d.String() != want[i] {
+			t.Errorf("expected %q, got %q", want[i], d)
+		}
+	}
+
+	if d := Lint(constantRule(true)); len(d) != 1 || d[0].Severity != SeverityError {
+		t.Errorf("expected an error for a rule that cannot be linted, got %v", d)
+	}
+}
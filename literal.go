@@ -0,0 +1,167 @@
+package rules
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// literal is a constant value written directly in a rule expression, or the
+// result of a function call. It behaves like a variable named after its source text.
+type literal struct {
+	name  string
+	value any
+}
+
+// isLiteral reports whether token is a string or number literal.
+func isLiteral(token string) bool {
+	return isStringLiteral(token) || isNumberLiteral(token)
+}
+
+func isStringLiteral(token string) bool {
+	return len(token) >= 2 && token[0] == '"' && token[len(token)-1] == '"'
+}
+
+func isNumberLiteral(token string) bool {
+	digits := strings.TrimPrefix(token, "-")
+	if digits == "" || !unicode.IsDigit([]rune(digits)[0]) {
+		return false
+	}
+	_, err := strconv.ParseFloat(token, 64)
+	return err == nil
+}
+
+// parseLiteral converts a literal token to a literal element.
+func parseLiteral(token string) (literal, bool) {
+	if isStringLiteral(token) {
+		s, err := strconv.Unquote(token)
+		if err != nil {
+			return literal{}, false
+		}
+		return literal{name: token, value: s}, true
+	}
+	if isNumberLiteral(token) {
+		f, err := strconv.ParseFloat(token, 64)
+		if err != nil {
+			return literal{}, false
+		}
+		return literal{name: token, value: f}, true
+	}
+	return literal{}, false
+}
+
+func (l literal) String() string {
+	return l.name
+}
+
+func (l literal) getType() string {
+	return "variable"
+}
+
+func (l literal) getName() string {
+	return l.name
+}
+
+func (l literal) getValue() any {
+	return l.value
+}
+
+func (l literal) withValue(value any) (Variable, bool) {
+	l.value = value
+	return l, true
+}
+
+func (l literal) equalTo(v2 Variable) Attribute {
+	c, _ := compareValues(l.value, v2.getValue())
+	return attribute{name: "(" + l.name + " == " + v2.getName() + ")", value: c == 0}
+}
+
+func (l literal) notEqualTo(v2 Variable) Attribute {
+	return l.equalTo(v2).not()
+}
+
+func (l literal) greaterThan(v2 Variable) Attribute {
+	c, _ := compareValues(l.value, v2.getValue())
+	return attribute{name: "(" + l.name + " > " + v2.getName() + ")", value: c > 0}
+}
+
+func (l literal) lessThan(v2 Variable) Attribute {
+	return l.greaterThanOrEqualTo(v2).not()
+}
+
+func (l literal) greaterThanOrEqualTo(v2 Variable) Attribute {
+	return l.greaterThan(v2).or(l.equalTo(v2))
+}
+
+func (l literal) lessThanOrEqualTo(v2 Variable) Attribute {
+	return l.greaterThan(v2).not()
+}
+
+// compareValues compares two values of possibly different types. Numbers are
+// compared by value regardless of their Go type, strings lexicographically and
+// booleans with false ordered before true. It returns false if the values are
+// not comparable with each other.
+func compareValues(a, b any) (int, bool) {
+	va, vb := reflect.ValueOf(a), reflect.ValueOf(b)
+	if !va.IsValid() || !vb.IsValid() {
+		return 0, false
+	}
+
+	switch {
+	case isInt(va.Kind()) && isInt(vb.Kind()):
+		return compareOrdered(va.Int(), vb.Int()), true
+	case isUint(va.Kind()) && isUint(vb.Kind()):
+		return compareOrdered(va.Uint(), vb.Uint()), true
+	case isNumber(va.Kind()) && isNumber(vb.Kind()):
+		return compareOrdered(toFloat(va), toFloat(vb)), true
+	case va.Kind() == reflect.String && vb.Kind() == reflect.String:
+		return strings.Compare(va.String(), vb.String()), true
+	case va.Kind() == reflect.Bool && vb.Kind() == reflect.Bool:
+		x, y := va.Bool(), vb.Bool()
+		switch {
+		case x == y:
+			return 0, true
+		case y:
+			return -1, true
+		default:
+			return 1, true
+		}
+	}
+
+	return 0, false
+}
+
+func compareOrdered[T ordered](a, b T) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+func isInt(k reflect.Kind) bool {
+	return k >= reflect.Int && k <= reflect.Int64
+}
+
+func isUint(k reflect.Kind) bool {
+	return k >= reflect.Uint && k <= reflect.Uintptr
+}
+
+func isNumber(k reflect.Kind) bool {
+	return isInt(k) || isUint(k) || k == reflect.Float32 || k == reflect.Float64
+}
+
+func toFloat(v reflect.Value) float64 {
+	switch {
+	case isInt(v.Kind()):
+		return float64(v.Int())
+	case isUint(v.Kind()):
+		return float64(v.Uint())
+	default:
+		return v.Float()
+	}
+}
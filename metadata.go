@@ -0,0 +1,108 @@
+package rules
+
+import (
+	"time"
+)
+
+// Metadata describes a rule for the people maintaining it. It takes no part in
+// the evaluation of the rule, except for the validity window, outside of which a
+// RuleSet skips the rule.
+type Metadata struct {
+	Description string
+	Tags        []string
+	Owner       string
+	Version     string
+	// ValidFrom is the time the rule takes effect at. The zero time means always.
+	ValidFrom time.Time
+	// ValidTo is the time the rule stops taking effect at. The zero time means never.
+	ValidTo time.Time
+	Labels  map[string]string
+}
+
+// HasTag reports whether the metadata holds the given tag.
+func (m Metadata) HasTag(tag string) bool {
+	return indexOf(m.Tags, tag) >= 0
+}
+
+// EffectiveAt reports whether t is within the validity window: at or after
+// ValidFrom, and before ValidTo.
+func (m Metadata) EffectiveAt(t time.Time) bool {
+	if !m.ValidFrom.IsZero() && t.Before(m.ValidFrom) {
+		return false
+	}
+	return m.ValidTo.IsZero() || t.Before(m.ValidTo)
+}
+
+func (m Metadata) clone() Metadata {
+	m.Tags = append([]string(nil), m.Tags...)
+	if m.Labels != nil {
+		labels := make(map[string]string, len(m.Labels))
+		for k, v := range m.Labels {
+			labels[k] = v
+		}
+		m.Labels = labels
+	}
+	return m
+}
+
+// MetadataOf returns the metadata of r, which is empty for rules not created by Parse.
+func MetadataOf(r Rule) Metadata {
+	if m, ok := r.(interface{ Metadata() Metadata }); ok {
+		return m.Metadata()
+	}
+	return Metadata{}
+}
+
+// WithMetadata attaches metadata to the rule, replacing what other options set.
+func WithMetadata(m Metadata) ParseOption {
+	return func(c *parseConfig) {
+		c.metadata = m.clone()
+	}
+}
+
+// WithDescription attaches a description to the rule.
+func WithDescription(description string) ParseOption {
+	return func(c *parseConfig) {
+		c.metadata.Description = description
+	}
+}
+
+// WithTags attaches tags to the rule.
+func WithTags(tags ...string) ParseOption {
+	return func(c *parseConfig) {
+		c.metadata.Tags = append(c.metadata.Tags, tags...)
+	}
+}
+
+// WithOwner records who owns the rule.
+func WithOwner(owner string) ParseOption {
+	return func(c *parseConfig) {
+		c.metadata.Owner = owner
+	}
+}
+
+// WithVersion records the version of the rule.
+func WithVersion(version string) ParseOption {
+	return func(c *parseConfig) {
+		c.metadata.Version = version
+	}
+}
+
+// WithValidity sets the window in which the rule takes effect. A zero time leaves
+// the window open on its side.
+func WithValidity(from, to time.Time) ParseOption {
+	return func(c *parseConfig) {
+		c.metadata.ValidFrom = from
+		c.metadata.ValidTo = to
+	}
+}
+
+// WithLabel attaches a label to the rule.
+func WithLabel(key, value string) ParseOption {
+	return func(c *parseConfig) {
+		if c.metadata.Labels == nil {
+			c.metadata.Labels = make(map[string]string)
+		}
+		c.metadata.Labels[key] = value
+	}
+}
@@ -0,0 +1,108 @@
+package rules
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestMetadata(t *testing.T) {
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)
+
+	r := MustParse("summerSale", "season EQ \"summer\"",
+		WithDescription("Summer sale discount."),
+		WithTags("pricing", "marketing"),
+		WithOwner("marketing"),
+		WithVersion("1.2"),
+		WithValidity(from, to),
+		WithLabel("ticket", "PRC-12"),
+	)
+
+	want := Metadata{
+		Description: "Summer sale discount.",
+		Tags:        []string{"pricing", "marketing"},
+		Owner:       "marketing",
+		Version:     "1.2",
+		ValidFrom:   from,
+		ValidTo:     to,
+		Labels:      map[string]string{"ticket": "PRC-12"},
+	}
+	m := MetadataOf(r)
+	if !reflect.DeepEqual(m, want) {
+		t.Errorf("expected %+v, got %+v", want, m)
+	}
+
+	m.Labels["ticket"] = "changed"
+	if MetadataOf(r).Labels["ticket"] != "PRC-12" {
+		t.Error("expected the metadata of the rule not to change")
+	}
+
+	tests := []struct {
+		at   time.Time
+		want bool
+	}{
+		{from.Add(-time.Second), false},
+		{from, true},
+		{to.Add(-time.Second), true},
+		{to, false},
+	}
+	for _, tt := range tests {
+		if got := m.EffectiveAt(tt.at); got != tt.want {
+			t.Errorf("EffectiveAt(%v): expected %v, got %v", tt.at, tt.want, got)
+		}
+	}
+
+	if m := MetadataOf(constantRule(true)); !reflect.DeepEqual(m, Metadata{}) {
+		t.Errorf("expected empty metadata, got %+v", m)
+	}
+}
+
+func TestRuleSetSkipsInactiveRules(t *testing.T) {
+	now := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	rs, err := NewRuleSetWithOptions([]Rule{
+		MustParse("current", "a", WithTags("pricing")),
+		MustParse("expired", "NOT a", WithTags("pricing"), WithValidity(time.Time{}, now)),
+		MustParse("upcoming", "NOT a", WithValidity(now.Add(time.Hour), time.Time{})),
+		MustParse("untagged", "a"),
+	}, WithClock(func() time.Time { return now }))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result := rs.EvaluateAll(NewContext(NewAttribute("a")(true)))
+	if !result.Result() {
+		t.Error("expected inactive rules not to fail the set")
+	}
+	var skipped []string
+	for _, res := range result.Skipped() {
+		skipped = append(skipped, res.Name)
+	}
+	if !reflect.DeepEqual(skipped, []string{"expired", "upcoming"}) {
+		t.Errorf("expected expired and upcoming to be skipped, got %v", skipped)
+	}
+
+	filtered, err := NewRuleSetWithOptions(rs.Rules(), WithTagFilter("pricing"), WithClock(func() time.Time { return now }))
+	if err != nil {
+		t.Fatal(err)
+	}
+	result = filtered.EvaluateAll(NewContext(NewAttribute("a")(true)))
+	var passed []string
+	for _, res := range result.Passed() {
+		passed = append(passed, res.Name)
+	}
+	if !reflect.DeepEqual(passed, []string{"current"}) {
+		t.Errorf("expected only current to pass, got %v", passed)
+	}
+}
+
+// constantRule is a rule not created by Parse.
+type constantRule bool
+
+func (r constantRule) Name() string {
+	return "constant"
+}
+
+func (r constantRule) Evaluate(RuleContext) (bool, error) {
+	return bool(r), nil
+}
@@ -0,0 +1,288 @@
+package rules
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Network evaluates a set of rules incrementally. The expressions of its rules are
+// compiled into a single graph in which identical sub-expressions are shared, so a
+// condition used by many rules is evaluated once. The network keeps the value of
+// every node, and when elements of the context change, only the nodes that depend
+// on them are evaluated again.
+//
+// Elements missing from the context, such as references to other rules, and
+// computed elements are evaluated again on every update, as the network cannot
+// tell what they depend on. Functions used by the rules are expected to be pure.
+type Network struct {
+	nodes []*netNode
+	keys  map[string]int
+	rules []netRule
+	index map[string]int
+
+	// leaves maps element names to the nodes reading them.
+	leaves map[string][]int
+
+	wm        *WorkingMemory
+	resolvers []ruleResolver
+	dirty     []bool
+
+	// evaluations counts the evaluated nodes, for tests.
+	evaluations int
+}
+
+// netNode is a node of a network: a token of a rule expression with its operands.
+// Operands always precede the node in the network, so evaluating nodes in order
+// evaluates operands first.
+type netNode struct {
+	token    string
+	rule     *rule
+	children []int
+	parents  []int
+
+	value RuleElement
+	err   error
+
+	// volatile is true for leaves that must be evaluated on every update.
+	volatile bool
+}
+
+type netRule struct {
+	rule *rule
+	root int
+}
+
+// NewNetwork compiles rules into a network. Rules must be created with Parse and
+// their names must be unique. The rules are evaluated against an empty context,
+// until the network is evaluated or updated.
+func NewNetwork(rules ...Rule) (*Network, error) {
+	n := &Network{
+		keys:   make(map[string]int),
+		index:  make(map[string]int),
+		leaves: make(map[string][]int),
+	}
+	for _, r := range rules {
+		if err := n.add(r); err != nil {
+			return nil, err
+		}
+	}
+	n.Evaluate(NewContext())
+	return n, nil
+}
+
+func (n *Network) add(r Rule) error {
+	rr, ok := r.(*rule)
+	if !ok {
+		return fmt.Errorf("%w: rule %s cannot be compiled into a network", ErrInvalidRule, r.Name())
+	}
+	if _, ok := n.index[rr.name]; ok {
+		return fmt.Errorf("%w: %s", ErrDuplicateRule, rr.name)
+	}
+	if rr.references != nil {
+		n.resolvers = append(n.resolvers, rr.references)
+	}
+
+	var st []int
+	for _, token := range rr.r {
+		children := make([]int, arity(token))
+		if len(children) > len(st) {
+			return fmt.Errorf("%w: missing operand for %s", ErrInvalidRule, token)
+		}
+		copy(children, st[len(st)-len(children):])
+		st = append(st[:len(st)-len(children)], n.node(rr, token, children))
+	}
+	if len(st) != 1 {
+		return fmt.Errorf("%w: %s", ErrInvalidExpression, rr.name)
+	}
+
+	n.index[rr.name] = len(n.rules)
+	n.rules = append(n.rules, netRule{rule: rr, root: st[0]})
+	return nil
+}
+
+// node returns the node for token with the given operands, creating it unless an
+// identical node already exists.
+func (n *Network) node(r *rule, token string, children []int) int {
+	key := nodeKey(r, token, children)
+	if i, ok := n.keys[key]; ok {
+		return i
+	}
+
+	i := len(n.nodes)
+	n.nodes = append(n.nodes, &netNode{token: token, rule: r, children: children})
+	n.keys[key] = i
+	for _, c := range children {
+		n.nodes[c].parents = append(n.nodes[c].parents, i)
+	}
+	if !isOperator(token) && !isLiteral(token) && !isCallToken(token) {
+		n.leaves[token] = append(n.leaves[token], i)
+	}
+	return i
+}
+
+// nodeKey identifies a node. Calls depend on the functions of the rule, and
+// identifiers on the rules it references, so they are only shared between rules
+// parsed with the same registries.
+func nodeKey(r *rule, token string, children []int) string {
+	var b strings.Builder
+	b.WriteString(token)
+	switch {
+	case isCallToken(token):
+		fmt.Fprintf(&b, "@%p", r.functions)
+	case !isOperator(token) && !isLiteral(token):
+		fmt.Fprintf(&b, "@%p", r.references)
+	}
+	for _, c := range children {
+		b.WriteByte(' ')
+		b.WriteString(strconv.Itoa(c))
+	}
+	return b.String()
+}
+
+func isCallToken(token string) bool {
+	_, _, ok := parseCall(token)
+	return ok
+}
+
+// Size returns the number of nodes of the network.
+func (n *Network) Size() int {
+	return len(n.nodes)
+}
+
+// Evaluate evaluates every rule of the network against ctx, which becomes the
+// context later updates apply to.
+func (n *Network) Evaluate(ctx RuleContext) {
+	n.wm = NewWorkingMemory(ctx.listElements()...)
+	n.dirty = make([]bool, len(n.nodes))
+	for i := range n.dirty {
+		n.dirty[i] = true
+	}
+	n.propagate()
+}
+
+// Update sets elems in the context of the network, replacing the elements with
+// the same names, and evaluates the nodes affected by the change. It returns the
+// names of the rules whose result changed, in the order they were added.
+func (n *Network) Update(elems ...RuleElement) []string {
+	version := n.wm.version
+	for _, elem := range elems {
+		n.wm.Set(elem)
+	}
+	for _, elem := range elems {
+		if !n.wm.changedSince(version, []string{elem.getName()}) {
+			continue
+		}
+		for _, i := range n.leaves[elem.getName()] {
+			n.dirty[i] = true
+		}
+	}
+	for i, node := range n.nodes {
+		if node.volatile {
+			n.dirty[i] = true
+		}
+	}
+
+	roots := make([]*netNode, len(n.rules))
+	before := make([]RuleElement, len(n.rules))
+	for i, r := range n.rules {
+		roots[i] = n.nodes[r.root]
+		before[i] = roots[i].value
+	}
+	n.propagate()
+
+	var changed []string
+	for i, r := range n.rules {
+		after := roots[i].value
+		if (after == nil) != (before[i] == nil) || (after != nil && !sameValue(after, before[i])) {
+			changed = append(changed, r.rule.name)
+		}
+	}
+	return changed
+}
+
+// propagate evaluates the dirty nodes in order, marking the parents of the nodes
+// whose value changed as dirty.
+func (n *Network) propagate() {
+	ev := newEvaluation(n.wm)
+	for _, resolver := range n.resolvers {
+		ev.addResolver(resolver)
+	}
+	ev.addResolver(n)
+
+	for i, node := range n.nodes {
+		if !n.dirty[i] {
+			continue
+		}
+		n.dirty[i] = false
+
+		value, err := n.evaluate(ev, node)
+		if sameValue(value, node.value) || (err != nil && node.err != nil && err.Error() == node.err.Error()) {
+			continue
+		}
+		node.value, node.err = value, err
+		for _, p := range node.parents {
+			n.dirty[p] = true
+		}
+	}
+}
+
+func (n *Network) evaluate(ev *evaluation, node *netNode) (RuleElement, error) {
+	n.evaluations++
+
+	args := make([]RuleElement, len(node.children))
+	for i, c := range node.children {
+		if err := n.nodes[c].err; err != nil {
+			return nil, err
+		}
+		args[i] = n.nodes[c].value
+	}
+
+	if len(args) == 0 && !isLiteral(node.token) && !isCallToken(node.token) {
+		el, ok := n.wm.findElement(node.token)
+		_, computed := el.(computedElement)
+		node.volatile = !ok || computed
+	}
+
+	return node.rule.apply(ev, node.token, args)
+}
+
+// Result returns the result of the named rule for the current context.
+func (n *Network) Result(name string) (bool, error) {
+	i, ok := n.index[name]
+	if !ok {
+		return false, fmt.Errorf("%w: %s", ErrMissingDataInContext, name)
+	}
+	return n.result(n.rules[i])
+}
+
+func (n *Network) result(r netRule) (bool, error) {
+	root := n.nodes[r.root]
+	if root.err != nil {
+		return false, root.err
+	}
+	a, ok := root.value.(Attribute)
+	if !ok {
+		return false, fmt.Errorf("%w: output %s is not an attribute", ErrInvalidRule, root.value.getName())
+	}
+	return a.getValue(), nil
+}
+
+// Results returns the results of the rules that evaluate without an error, by rule name.
+func (n *Network) Results() map[string]bool {
+	results := make(map[string]bool, len(n.rules))
+	for _, r := range n.rules {
+		if result, err := n.result(r); err == nil {
+			results[r.rule.name] = result
+		}
+	}
+	return results
+}
+
+func (n *Network) lookupRule(name string) (Rule, bool) {
+	i, ok := n.index[name]
+	if !ok {
+		return nil, false
+	}
+	return n.rules[i].rule, true
+}
@@ -0,0 +1,166 @@
+package rules
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestNetwork(t *testing.T) {
+	adult := MustParse("adult", "age GTE 18 AND country EQ \"pl\"")
+	senior := MustParse("senior", "age GTE 65 AND country EQ \"pl\"")
+	discount := MustParse("discount", "(age GTE 18 AND country EQ \"pl\") AND member")
+
+	n, err := NewNetwork(adult, senior, discount)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// age, 18, GTE, country, "pl", EQ, AND, 65, GTE, AND, member, AND
+	if n.Size() != 12 {
+		t.Errorf("expected 12 shared nodes, got %d", n.Size())
+	}
+
+	n.Evaluate(NewContext(
+		NewVariable[int]("age")(30),
+		NewVariable[string]("country")("pl"),
+		NewAttribute("member")(false),
+	))
+	if got := n.Results(); !reflect.DeepEqual(got, map[string]bool{"adult": true, "senior": false, "discount": false}) {
+		t.Errorf("unexpected results %v", got)
+	}
+
+	n.evaluations = 0
+	changed := n.Update(NewAttribute("member")(true))
+	if !reflect.DeepEqual(changed, []string{"discount"}) {
+		t.Errorf("expected discount to change, got %v", changed)
+	}
+	if n.evaluations != 2 {
+		t.Errorf("expected 2 evaluated nodes, got %d", n.evaluations)
+	}
+
+	n.evaluations = 0
+	changed = n.Update(NewVariable[int]("age")(31))
+	if len(changed) != 0 {
+		t.Errorf("expected no changes, got %v", changed)
+	}
+	// The age leaf and both comparisons; their results do not change.
+	if n.evaluations != 3 {
+		t.Errorf("expected 3 evaluated nodes, got %d", n.evaluations)
+	}
+
+	changed = n.Update(NewVariable[string]("country")("de"))
+	if !reflect.DeepEqual(changed, []string{"adult", "discount"}) {
+		t.Errorf("unexpected changes %v", changed)
+	}
+
+	n.evaluations = 0
+	if changed := n.Update(NewVariable[string]("country")("de")); len(changed) != 0 || n.evaluations != 0 {
+		t.Errorf("expected nothing to be evaluated, got %v after %d evaluations", changed, n.evaluations)
+	}
+}
+
+func TestNetworkMatchesRules(t *testing.T) {
+	rules := []Rule{
+		MustParse("a", "x GT 10 OR (y AND NOT z)"),
+		MustParse("b", "LEN(name) GTE 3 AND x LT 100"),
+		MustParse("c", "NOT (y AND NOT z) XOR x EQ 5"),
+	}
+	n, err := NewNetwork(rules...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := NewWorkingMemory(
+		NewVariable[int]("x")(5),
+		NewAttribute("y")(true),
+		NewAttribute("z")(false),
+		NewVariable[string]("name")("ab"),
+	)
+	n.Evaluate(ctx)
+
+	updates := []RuleElement{
+		NewVariable[int]("x")(11),
+		NewAttribute("z")(true),
+		NewVariable[string]("name")("abc"),
+		NewVariable[int]("x")(200),
+		NewAttribute("y")(false),
+		NewVariable[int]("x")(5),
+	}
+	for _, u := range updates {
+		n.Update(u)
+		ctx.Set(u)
+		for _, r := range rules {
+			want, wantErr := r.Evaluate(ctx)
+			got, err := n.Result(r.Name())
+			if got != want || (err == nil) != (wantErr == nil) {
+				t.Errorf("after %v: %s: expected %v (%v), got %v (%v)", u, r.Name(), want, wantErr, got, err)
+			}
+		}
+	}
+}
+
+func TestNetworkVolatileElements(t *testing.T) {
+	base := MustParse("base", "x GT 1")
+	derived := MustParse("derived", "base AND big")
+
+	n, err := NewNetwork(base, derived)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := n.Result("derived"); !errors.Is(err, ErrMissingDataInContext) {
+		t.Errorf("expected ErrMissingDataInContext, got %v", err)
+	}
+
+	big := NewComputedAttribute("big", func(ctx RuleContext) (bool, error) {
+		x, err := VariableValue[int](ctx, "x")
+		return x > 100, err
+	})
+	n.Evaluate(NewContext(NewVariable[int]("x")(2), big))
+	if ok, err := n.Result("derived"); err != nil || ok {
+		t.Errorf("expected false, got %v (%v)", ok, err)
+	}
+
+	changed := n.Update(NewVariable[int]("x")(200))
+	if !reflect.DeepEqual(changed, []string{"derived"}) {
+		t.Errorf("expected derived to change, got %v", changed)
+	}
+}
+
+func TestNetworkErrors(t *testing.T) {
+	if _, err := NewNetwork(MustParse("a", "x"), MustParse("a", "y")); !errors.Is(err, ErrDuplicateRule) {
+		t.Errorf("expected ErrDuplicateRule, got %v", err)
+	}
+
+	n, err := NewNetwork(MustParse("a", "x"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := n.Result("b"); err == nil {
+		t.Error("expected an error for an unknown rule")
+	}
+}
+
+func BenchmarkNetworkUpdate(b *testing.B) {
+	var rules []Rule
+	for i := 0; i < 800; i++ {
+		rules = append(rules, MustParse(fmt.Sprintf("r%d", i),
+			fmt.Sprintf("nights GTE %d AND (country EQ \"pl\" OR member) AND guests LTE %d", i%14, i%6+1)))
+	}
+	n, err := NewNetwork(rules...)
+	if err != nil {
+		b.Fatal(err)
+	}
+	n.Evaluate(NewContext(
+		NewVariable[int]("nights")(3),
+		NewVariable[string]("country")("pl"),
+		NewAttribute("member")(false),
+		NewVariable[int]("guests")(2),
+	))
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		n.Update(NewVariable[int]("guests")(i % 6))
+	}
+}
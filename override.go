@@ -0,0 +1,141 @@
+package rules
+
+import (
+	"errors"
+	"time"
+)
+
+// Override is a RuleOverride that applies only under some conditions, and can
+// force the result of the overridden rule instead of skipping it. It also records
+// why and by whom it was put in place.
+type Override struct {
+	rule      string
+	condition Rule
+	until     time.Time
+	scope     map[string]any
+	reason    string
+	author    string
+	forced    bool
+	result    bool
+}
+
+// OverrideOption configures an Override.
+type OverrideOption func(*Override)
+
+// NewOverride creates an override of the rule with the given name. Without options
+// it skips the rule unconditionally, like passing the rule to AddOverride.
+func NewOverride(rule string, opts ...OverrideOption) *Override {
+	o := &Override{
+		rule:  rule,
+		scope: make(map[string]any),
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// OverrideWhen makes the override apply only when condition evaluates to true
+// against the context the rule set is evaluated with.
+func OverrideWhen(condition Rule) OverrideOption {
+	return func(o *Override) {
+		o.condition = condition
+	}
+}
+
+// OverrideUntil makes the override expire at the given time.
+func OverrideUntil(t time.Time) OverrideOption {
+	return func(o *Override) {
+		o.until = t
+	}
+}
+
+// OverrideScope makes the override apply only to contexts in which the element
+// with the given name holds the given value, such as a flight number.
+func OverrideScope(name string, value any) OverrideOption {
+	return func(o *Override) {
+		o.scope[name] = value
+	}
+}
+
+// OverrideReason records why and by whom the override was put in place.
+func OverrideReason(reason, author string) OverrideOption {
+	return func(o *Override) {
+		o.reason = reason
+		o.author = author
+	}
+}
+
+// OverrideResult makes the override replace the result of the rule with the given
+// value instead of skipping the rule.
+func OverrideResult(result bool) OverrideOption {
+	return func(o *Override) {
+		o.forced = true
+		o.result = result
+	}
+}
+
+// Name returns the name of the overridden rule.
+func (o *Override) Name() string {
+	return o.rule
+}
+
+// Reason returns why the override was put in place.
+func (o *Override) Reason() string {
+	return o.reason
+}
+
+// Author returns who put the override in place.
+func (o *Override) Author() string {
+	return o.author
+}
+
+// Until returns the time the override expires at, or the zero time if it never expires.
+func (o *Override) Until() time.Time {
+	return o.until
+}
+
+// Result returns the result the override forces, and whether it forces one at all.
+func (o *Override) Result() (bool, bool) {
+	return o.result, o.forced
+}
+
+// applies reports whether the override applies to an evaluation taking place at now.
+// It does not apply to contexts missing an element of its scope.
+func (o *Override) applies(ev *evaluation, now time.Time) (bool, error) {
+	if !o.until.IsZero() && !now.Before(o.until) {
+		return false, nil
+	}
+
+	for name, want := range o.scope {
+		el, err := ev.resolve(name)
+		if errors.Is(err, ErrMissingDataInContext) {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		var value any
+		switch v := el.(type) {
+		case Attribute:
+			value = v.getValue()
+		case Variable:
+			value = v.getValue()
+		}
+		if c, ok := compareValues(value, want); !ok || c != 0 {
+			return false, nil
+		}
+	}
+
+	if o.condition == nil {
+		return true, nil
+	}
+	return o.condition.Evaluate(ev)
+}
+
+// WithClock sets the function used to check whether overrides have expired.
+func WithClock(now func() time.Time) RuleSetOption {
+	return func(r *ruleSet) {
+		r.now = now
+	}
+}
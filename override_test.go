@@ -0,0 +1,165 @@
+package rules
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestOverrides(t *testing.T) {
+	var smart = NewAttribute("smart")
+	var economy = NewAttribute("economy")
+	var flight = NewVariable[string]("flight")
+
+	friday := time.Date(2026, 10, 23, 0, 0, 0, 0, time.UTC)
+	thursday := friday.Add(-24 * time.Hour)
+
+	dressCode := MustParse("dressCode", "smart")
+	class := MustParse("class", "economy")
+
+	tests := []struct {
+		name     string
+		override RuleOverride
+		now      time.Time
+		ctx      RuleContext
+		want     bool
+	}{
+		{
+			name:     "unconditional",
+			override: NewOverride("dressCode"),
+			ctx:      NewContext(smart(false), economy(true), flight("LO123")),
+			want:     true,
+		},
+		{
+			name:     "in scope before expiry",
+			override: NewOverride("dressCode", OverrideScope("flight", "LO123"), OverrideUntil(friday)),
+			now:      thursday,
+			ctx:      NewContext(smart(false), economy(true), flight("LO123")),
+			want:     true,
+		},
+		{
+			name:     "expired",
+			override: NewOverride("dressCode", OverrideScope("flight", "LO123"), OverrideUntil(friday)),
+			now:      friday,
+			ctx:      NewContext(smart(false), economy(true), flight("LO123")),
+			want:     false,
+		},
+		{
+			name:     "out of scope",
+			override: NewOverride("dressCode", OverrideScope("flight", "LO123")),
+			ctx:      NewContext(smart(false), economy(true), flight("LO456")),
+			want:     false,
+		},
+		{
+			name:     "scope missing",
+			override: NewOverride("dressCode", OverrideScope("flight", "LO123")),
+			ctx:      NewContext(smart(false), economy(true)),
+			want:     false,
+		},
+		{
+			name:     "condition holds",
+			override: NewOverride("dressCode", OverrideWhen(MustParse("cond", `flight EQ "LO123" AND economy`))),
+			ctx:      NewContext(smart(false), economy(true), flight("LO123")),
+			want:     true,
+		},
+		{
+			name:     "condition does not hold",
+			override: NewOverride("dressCode", OverrideWhen(MustParse("cond", `NOT economy`))),
+			ctx:      NewContext(smart(false), economy(true), flight("LO123")),
+			want:     false,
+		},
+		{
+			name:     "forced false",
+			override: NewOverride("class", OverrideResult(false)),
+			ctx:      NewContext(smart(true), economy(true), flight("LO123")),
+			want:     false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rs, err := NewRuleSetWithOptions(
+				[]Rule{dressCode, class},
+				WithClock(func() time.Time { return tt.now }),
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+			rs.AddOverride(tt.override)
+
+			got, err := rs.Evaluate(tt.ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("Evaluate() = %v, want %v", got, tt.want)
+			}
+			if all := rs.EvaluateAll(tt.ctx).Result(); all != tt.want {
+				t.Errorf("EvaluateAll().Result() = %v, want %v", all, tt.want)
+			}
+		})
+	}
+}
+
+func TestOverrideScopeError(t *testing.T) {
+	errLookup := errors.New("flight lookup failed")
+	flight := NewComputedVariable("flight", func(ctx RuleContext) (string, error) {
+		return "", errLookup
+	})
+
+	rs := NewRuleSet(MustParse("dressCode", "smart"))
+	rs.AddOverride(NewOverride("dressCode", OverrideScope("flight", "LO123")))
+
+	if _, err := rs.Evaluate(NewContext(NewAttribute("smart")(true), flight)); !errors.Is(err, errLookup) {
+		t.Errorf("Evaluate() error = %v, want %v", err, errLookup)
+	}
+}
+
+func TestOverrideReferencedRule(t *testing.T) {
+	var formal = NewAttribute("formal")
+	var ticket = NewAttribute("ticket")
+
+	tests := []struct {
+		name     string
+		override RuleOverride
+		want     bool
+	}{
+		{name: "forced", override: NewOverride("dress", OverrideResult(true)), want: true},
+		{name: "forced false", override: NewOverride("dress", OverrideResult(false)), want: false},
+		{name: "skipped", override: NewOverride("dress"), want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rs := NewRuleSet(MustParse("dress", "formal"), MustParse("board", "dress AND ticket"))
+			rs.AddOverride(tt.override)
+
+			result := rs.EvaluateAll(NewContext(formal(false), ticket(true)))
+			if err := result.Err(); err != nil {
+				t.Fatal(err)
+			}
+			board := result.Results[1]
+			if board.Name != "board" || board.Result != tt.want {
+				t.Errorf("EvaluateAll() board = %+v, want a result of %v", board, tt.want)
+			}
+		})
+	}
+}
+
+func TestOverrideAudit(t *testing.T) {
+	var smart = NewAttribute("smart")
+
+	rs := NewRuleSet(MustParse("dressCode", "smart"))
+	rs.AddOverride(NewOverride("dressCode", OverrideResult(true), OverrideReason("uniform delivery delayed", "ops")))
+
+	result := rs.EvaluateAll(NewContext(smart(false)))
+	res := result.Results[0]
+	if !res.Overridden || !res.Forced || !res.Result {
+		t.Fatalf("EvaluateAll() = %+v, want a forced true result", res)
+	}
+	o := res.Override.(*Override)
+	if o.Reason() != "uniform delivery delayed" || o.Author() != "ops" {
+		t.Errorf("Override = %q by %q", o.Reason(), o.Author())
+	}
+	if len(result.Passed()) != 1 || len(result.Skipped()) != 0 {
+		t.Errorf("Passed() = %v, Skipped() = %v", result.Passed(), result.Skipped())
+	}
+}
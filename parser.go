@@ -1,18 +1,52 @@
 package rules
 
 import (
+	"fmt"
+	"strconv"
 	"strings"
 	"unicode"
+	"unicode/utf8"
 
 	"github.com/IAmRadek/rules/internal/utils/stack"
 )
 
+// ParseOption configures how an expression is parsed.
+type ParseOption func(*parseConfig)
+
+type parseConfig struct {
+	functions   *FunctionRegistry
+	rules       *RuleRegistry
+	definitions *Definitions
+	metadata    Metadata
+}
+
+// WithFunctions makes the functions of the registry callable from the expression.
+func WithFunctions(functions *FunctionRegistry) ParseOption {
+	return func(c *parseConfig) {
+		c.functions = functions
+	}
+}
+
 // Parse parses a rule expression and returns a Rule.
 // The expression is a string that contains a boolean expression.
 // The expression can contain the following operators:
 //   - AND, OR, XOR, NOT, EQ, NEQ, GT, LT, GTE, LTE
-func Parse(name, expr string) (Rule, error) {
-	tokens, err := tokenize(expr)
+//
+// Operands are names of context elements, string literals such as "pl",
+// number literals such as 4.5, and function calls such as LOWER(country).
+// Line comments (// ...) and block comments (/* ... */) are kept with the rule
+// and printed back by Format.
+func Parse(name, expr string, opts ...ParseOption) (Rule, error) {
+	cfg := parseConfig{}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	tokens, trailing, err := scan(expr)
+	if err != nil {
+		return nil, err
+	}
+	tokens, err = cfg.definitions.expand(tokens, 0)
 	if err != nil {
 		return nil, err
 	}
@@ -20,20 +54,51 @@ func Parse(name, expr string) (Rule, error) {
 		return nil, ErrEmptyExpression
 	}
 
-	output := parse(tokens)
+	rpn := parseTokens(tokens)
+	output := texts(rpn)
 
 	if !isValid(output) {
 		return nil, ErrInvalidExpression
 	}
+	if err := checkCalls(output, cfg.functions); err != nil {
+		return nil, err
+	}
 
-	return &rule{name, output}, nil
+	r := &rule{
+		name:       name,
+		r:          output,
+		functions:  cfg.functions,
+		references: cfg.rules,
+		metadata:   cfg.metadata.clone(),
+		source:     expr,
+		tokens:     rpn,
+	}
+	for i, t := range rpn {
+		if len(t.comments) > 0 {
+			r.addComments(i, t.comments)
+		}
+	}
+	if len(trailing) > 0 {
+		r.addComments(len(output), trailing)
+	}
+
+	return r, nil
 }
 
 func isValid(output []string) bool {
 	s := make([]bool, 0)
 
 	for _, token := range output {
-		if isOperator(token) {
+		if token == "," {
+			return false
+		}
+		if _, arity, ok := parseCall(token); ok {
+			if len(s) < arity {
+				return false
+			}
+			s = s[:len(s)-arity]
+			s = append(s, true)
+		} else if isOperator(token) {
 			if token == kNOT {
 				if len(s) < 1 {
 					return false
@@ -54,8 +119,51 @@ func isValid(output []string) bool {
 	return len(s) == 1
 }
 
-func MustParse(name, expr string) Rule {
-	r, err := Parse(name, expr)
+// checkCalls verifies that every called function exists and that the arguments
+// whose kind is known at parse time match its parameters.
+func checkCalls(output []string, functions *FunctionRegistry) error {
+	s := stack.Stack[valueKind]{}
+
+	for _, token := range output {
+		if name, arity, ok := parseCall(token); ok {
+			fun, ok := functions.lookup(name)
+			if !ok {
+				return fmt.Errorf("%w: %s", ErrUnknownFunction, name)
+			}
+			if len(fun.params) != arity {
+				return fmt.Errorf("%w: %s expects %d arguments, got %d", ErrInvalidExpression, name, len(fun.params), arity)
+			}
+			for i := arity - 1; i >= 0; i-- {
+				if k := s.MustPop(); !fun.accepts(i, k) {
+					return fmt.Errorf("%w: argument %d of %s must be %s, got %s", ErrInvalidExpression, i+1, name, kindOf(fun.params[i]), k)
+				}
+			}
+			s.Push(kindOf(fun.result))
+			continue
+		}
+
+		switch {
+		case token == kNOT:
+			s.MustPop()
+			s.Push(kindBool)
+		case isOperator(token):
+			s.MustPop()
+			s.MustPop()
+			s.Push(kindBool)
+		case isStringLiteral(token):
+			s.Push(kindString)
+		case isNumberLiteral(token):
+			s.Push(kindNumber)
+		default:
+			s.Push(kindUnknown)
+		}
+	}
+
+	return nil
+}
+
+func MustParse(name, expr string, opts ...ParseOption) Rule {
+	r, err := Parse(name, expr, opts...)
 	if err != nil {
 		panic(err)
 	}
@@ -63,36 +171,98 @@ func MustParse(name, expr string) Rule {
 }
 
 func parse(tokens []string) []string {
-	output := make([]string, 0, len(tokens))
-	s := stack.Stack[string]{}
-	for _, token := range tokens {
+	return texts(parseTokens(newTokens(tokens)))
+}
+
+// parseTokens converts tokens to reverse polish notation. Comments of parentheses
+// and commas are moved to the next token, so that each output token carries the
+// comments written right before it.
+func parseTokens(tokens []token) []token {
+	output := make([]token, 0, len(tokens))
+	s := stack.Stack[token]{}
+	arities := stack.Stack[int]{}
+	expectArg := false
+	var pending []string
+
+	for i, tok := range tokens {
+		token := tok.text
+		if expectArg && token != ")" && token != "," {
+			arities.Push(arities.MustPop() + 1)
+		}
+		expectArg = false
+
+		tok.comments = append(pending, tok.comments...)
+		pending = nil
+
 		switch token {
 		case kAND, kOR, kXOR, kEQ, kNEQ, kGT, kLT, kGTE, kLTE:
 			p, ok := s.Peek()
-			for ok && precedence[p] >= precedence[token] {
+			for ok && precedence[p.text] >= precedence[token] {
 				output = append(output, s.MustPop())
 				p, ok = s.Peek()
 			}
-			s.Push(token)
+			s.Push(tok)
 		case kNOT:
-			s.Push(token)
+			s.Push(tok)
 		case "(":
-			s.Push(token)
+			if p, ok := s.Peek(); ok && isCallMarker(p.text) {
+				expectArg = true
+			}
+			pending = tok.comments
+			tok.comments = nil
+			s.Push(tok)
+		case ",":
+			pending = tok.comments
+			p, ok := s.Peek()
+			for ok && p.text != "(" {
+				output = append(output, s.MustPop())
+				p, ok = s.Peek()
+			}
+			if ok && isCallArgument(&s) {
+				expectArg = true
+			} else {
+				// A comma outside of a call's argument list makes the output invalid.
+				output = append(output, tok)
+			}
 		case ")":
+			pending = tok.comments
 			p, ok := s.Peek()
-			for ok && p != "(" {
+			for ok && p.text != "(" {
 				output = append(output, s.MustPop())
 				p, ok = s.Peek()
 			}
-			if ok && p == "(" {
+			closed := ok && p.text == "("
+			open := p
+			if closed {
 				s.MustPop()
 			}
 			p, ok = s.Peek()
-			if ok && p == kNOT {
+			if closed && (!ok || !isCallMarker(p.text)) && len(output) > 0 {
+				// The parentheses group an expression, whose root is the last output token.
+				root := &output[len(output)-1]
+				root.grouped = true
+				if !open.expanded {
+					root.parens++
+					root.parenPos = open.pos
+				}
+			}
+			if ok && isCallMarker(p.text) {
+				call := s.MustPop()
+				call.text += strconv.Itoa(arities.MustPop())
+				output = append(output, call)
+			}
+			p, ok = s.Peek()
+			if ok && p.text == kNOT {
 				output = append(output, s.MustPop())
 			}
 		default:
-			output = append(output, token)
+			if i+1 < len(tokens) && tokens[i+1].text == "(" && !isLiteral(token) {
+				tok.text += "/"
+				s.Push(tok)
+				arities.Push(0)
+				continue
+			}
+			output = append(output, tok)
 		}
 	}
 	for !s.IsEmpty() {
@@ -101,6 +271,33 @@ func parse(tokens []string) []string {
 	return output
 }
 
+// isCallArgument reports whether the parenthesis on top of the stack opens a call's argument list.
+func isCallArgument(s *stack.Stack[token]) bool {
+	paren := s.MustPop()
+	defer s.Push(paren)
+
+	p, ok := s.Peek()
+	return ok && isCallMarker(p.text)
+}
+
+// isCallMarker reports whether the operator stack entry p marks a pending function call.
+func isCallMarker(p string) bool {
+	return strings.HasSuffix(p, "/")
+}
+
+// parseCall splits a function call token of the form NAME/ARITY.
+func parseCall(token string) (string, int, bool) {
+	i := strings.LastIndexByte(token, '/')
+	if i <= 0 || isStringLiteral(token) {
+		return "", 0, false
+	}
+	arity, err := strconv.Atoi(token[i+1:])
+	if err != nil {
+		return "", 0, false
+	}
+	return token[:i], arity, true
+}
+
 var precedence = map[string]int{
 	kNOT: 3,
 	kAND: 1,
@@ -119,68 +316,195 @@ func isOperator(char string) bool {
 	return ok
 }
 
-func sanitize(expr string) string {
-	skipChar := rune(-1)
+// isComparison reports whether token is one of the comparison operators.
+func isComparison(token string) bool {
+	return precedence[token] == precedence[kEQ]
+}
 
-	mapper := func(r rune) rune {
-		if r == '\n' || unicode.IsSpace(r) {
-			return ' '
-		}
-		if r == '\u00A0' {
-			return skipChar
-		}
-		if !unicode.IsDigit(r) && !unicode.IsLetter(r) && r != '(' && r != ')' {
-			return skipChar
-		}
+// arity returns the number of operands a token of an expression in reverse polish notation takes.
+func arity(token string) int {
+	switch {
+	case token == kNOT:
+		return 1
+	case isOperator(token):
+		return 2
+	default:
+		_, n, _ := parseCall(token)
+		return n
+	}
+}
 
-		return r
+// isIdentRune reports whether r can be part of a name or a number.
+// Other characters outside string literals are ignored.
+func isIdentRune(r rune) bool {
+	return unicode.IsDigit(r) || unicode.IsLetter(r) || r == '_' || r == '.'
+}
+
+// token is a lexical token of an expression, along with its byte offset in the
+// source and the comments written right before it.
+type token struct {
+	text     string
+	pos      int
+	comments []string
+
+	// expanded is true for tokens inserted by the expansion of a macro.
+	expanded bool
+	// grouped is true for the last token of a parenthesised expression in reverse
+	// polish notation, which is the root of the expression.
+	grouped bool
+	// parens is the number of parentheses written around the expression, and
+	// parenPos the offset of the outermost one. Parentheses inserted by the
+	// expansion of a macro are not counted.
+	parens   int
+	parenPos int
+}
+
+func newTokens(texts []string) []token {
+	tokens := make([]token, len(texts))
+	for i, text := range texts {
+		tokens[i] = token{text: text}
+	}
+	return tokens
+}
+
+func texts(tokens []token) []string {
+	texts := make([]string, len(tokens))
+	for i, t := range tokens {
+		texts[i] = t.text
 	}
-	return strings.TrimSpace(strings.Map(mapper, expr))
+	return texts
 }
 
 func tokenize(expr string) ([]string, error) {
-	expr = sanitize(expr)
+	tokens, _, err := scan(expr)
+	if err != nil {
+		return nil, err
+	}
+	return texts(tokens), nil
+}
 
-	tokens := make([]string, 0, len(expr))
+// scan splits expr into tokens. Comments are attached to the token following them;
+// comments after the last token are returned separately.
+func scan(expr string) ([]token, []string, error) {
+	runes := []rune(expr)
+
+	tokens := make([]token, 0, len(runes))
 	currentToken := strings.Builder{}
+	currentPos := 0
 	parenCount := 0
+	offset := 0
+	var comments []string
+
+	emit := func(text string, pos int) {
+		tokens = append(tokens, token{text: text, pos: pos, comments: comments})
+		comments = nil
+	}
+	flush := func() {
+		if currentToken.Len() > 0 {
+			emit(currentToken.String(), currentPos)
+			currentToken.Reset()
+		}
+	}
 
-	for _, char := range expr {
-		switch char {
-		case '(':
+	for i := 0; i < len(runes); i++ {
+		char := runes[i]
+		pos := offset
+		offset += utf8.RuneLen(char)
+
+		switch {
+		case char == '(':
 			parenCount++
-			if currentToken.Len() > 0 {
-				tokens = append(tokens, currentToken.String())
-				currentToken.Reset()
-			}
-			tokens = append(tokens, string(char))
-		case ')':
+			flush()
+			emit(string(char), pos)
+		case char == ')':
 			parenCount--
 			if parenCount < 0 {
-				return nil, ErrMismatchedParentheses
+				return nil, nil, ErrMismatchedParentheses
 			}
-			if currentToken.Len() > 0 {
-				tokens = append(tokens, currentToken.String())
-				currentToken.Reset()
+			flush()
+			emit(string(char), pos)
+		case char == ',':
+			flush()
+			emit(string(char), pos)
+		case char == '"':
+			flush()
+			lit, n, err := scanString(runes[i:])
+			if err != nil {
+				return nil, nil, err
 			}
-			tokens = append(tokens, string(char))
-		case ' ':
-			if currentToken.Len() > 0 {
-				tokens = append(tokens, currentToken.String())
-				currentToken.Reset()
+			emit(lit, pos)
+			offset += len(string(runes[i+1 : i+n]))
+			i += n - 1
+		case char == '/' && i+1 < len(runes) && (runes[i+1] == '/' || runes[i+1] == '*'):
+			flush()
+			comment, n, err := scanComment(runes[i:])
+			if err != nil {
+				return nil, nil, err
+			}
+			comments = append(comments, comment)
+			offset += len(string(runes[i+1 : i+n]))
+			i += n - 1
+		case unicode.IsSpace(char):
+			flush()
+		case char == '-' && currentToken.Len() == 0 && i+1 < len(runes) && unicode.IsDigit(runes[i+1]):
+			currentPos = pos
+			currentToken.WriteRune(char)
+		case isIdentRune(char):
+			if currentToken.Len() == 0 {
+				currentPos = pos
 			}
-		default:
 			currentToken.WriteRune(char)
 		}
 	}
 
-	if currentToken.Len() > 0 {
-		tokens = append(tokens, currentToken.String())
-	}
+	flush()
 
 	if parenCount != 0 {
-		return nil, ErrMismatchedParentheses
+		return nil, nil, ErrMismatchedParentheses
 	}
 
-	return tokens, nil
+	return tokens, comments, nil
+}
+
+// scanComment reads the // or /* */ comment at the start of runes and returns it
+// without surrounding whitespace, along with the number of runes consumed.
+func scanComment(runes []rune) (string, int, error) {
+	if runes[1] == '/' {
+		end := len(runes)
+		for i, r := range runes {
+			if r == '\n' {
+				end = i
+				break
+			}
+		}
+		return strings.TrimSpace(string(runes[:end])), end, nil
+	}
+
+	for i := 2; i+1 < len(runes); i++ {
+		if runes[i] == '*' && runes[i+1] == '/' {
+			return strings.TrimSpace(string(runes[:i+2])), i + 2, nil
+		}
+	}
+	return "", 0, fmt.Errorf("%w: unterminated comment", ErrInvalidExpression)
+}
+
+// scanString reads the string literal at the start of runes and returns it in its
+// canonical quoted form along with the number of runes consumed.
+func scanString(runes []rune) (string, int, error) {
+	escaped := false
+	for i := 1; i < len(runes); i++ {
+		switch {
+		case escaped:
+			escaped = false
+		case runes[i] == '\\':
+			escaped = true
+		case runes[i] == '"':
+			s, err := strconv.Unquote(string(runes[:i+1]))
+			if err != nil {
+				return "", 0, fmt.Errorf("%w: invalid string literal %s", ErrInvalidExpression, string(runes[:i+1]))
+			}
+			return strconv.Quote(s), i + 1, nil
+		}
+	}
+	return "", 0, fmt.Errorf("%w: unterminated string literal", ErrInvalidExpression)
 }
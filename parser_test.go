@@ -58,6 +58,16 @@ B`,
 			expr: "A OR B AND (C EQ D)",
 			want: []string{"A", "OR", "B", "AND", "(", "C", "EQ", "D", ")"},
 		},
+		{
+			name: "literals",
+			expr: `name EQ "Jan \"J\" K" AND miles GT -12.5`,
+			want: []string{"name", "EQ", `"Jan \"J\" K"`, "AND", "miles", "GT", "-12.5"},
+		},
+		{
+			name: "function call",
+			expr: "DAYS_BETWEEN(a, b) LT 5",
+			want: []string{"DAYS_BETWEEN", "(", "a", ",", "b", ")", "LT", "5"},
+		},
 		{
 			name:    "missing closing parenthesis",
 			expr:    "A OR B AND (C EQ D",
@@ -150,6 +160,21 @@ func TestParse(t *testing.T) {
 			tokens: []string{"A", "AND", "B", "AND", "C", "EQ", "D", "AND", "E", "EQ", "F"},
 			want:   []string{"A", "B", "AND", "C", "D", "EQ", "AND", "E", "F", "EQ", "AND"},
 		},
+		{
+			name:   "LOWER(C) EQ D",
+			tokens: []string{"LOWER", "(", "C", ")", "EQ", "D"},
+			want:   []string{"C", "LOWER/1", "D", "EQ"},
+		},
+		{
+			name:   "NOT F(A, G(B), C)",
+			tokens: []string{"NOT", "F", "(", "A", ",", "G", "(", "B", ")", ",", "C", ")"},
+			want:   []string{"A", "B", "G/1", "C", "F/3", "NOT"},
+		},
+		{
+			name:   "NOW() GT A",
+			tokens: []string{"NOW", "(", ")", "GT", "A"},
+			want:   []string{"NOW/0", "A", "GT"},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
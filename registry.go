@@ -0,0 +1,114 @@
+package rules
+
+import (
+	"fmt"
+)
+
+// ruleResolver finds rules that other rules reference by name.
+type ruleResolver interface {
+	lookupRule(name string) (Rule, bool)
+}
+
+// RuleRegistry holds named rules that can be referenced from other rule expressions.
+// A rule parsed with WithRules resolves names missing from the evaluated context
+// to rules of the registry. The result of a referenced rule is computed at most
+// once per evaluation.
+type RuleRegistry struct {
+	rules map[string]Rule
+}
+
+// NewRuleRegistry creates a registry holding the given rules.
+func NewRuleRegistry(rules ...Rule) (*RuleRegistry, error) {
+	r := &RuleRegistry{
+		rules: make(map[string]Rule),
+	}
+	for _, rule := range rules {
+		if err := r.Register(rule); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+// Register adds a rule to the registry. It fails if a rule with the same name is
+// already registered, or if the rule would close a cycle of references.
+func (r *RuleRegistry) Register(rule Rule) error {
+	if _, ok := r.rules[rule.Name()]; ok {
+		return fmt.Errorf("%w: %s", ErrDuplicateRule, rule.Name())
+	}
+	if err := checkCycles(rule, r.lookupRule); err != nil {
+		return err
+	}
+	r.rules[rule.Name()] = rule
+	return nil
+}
+
+// Rule returns the registered rule with the given name.
+func (r *RuleRegistry) Rule(name string) (Rule, bool) {
+	return r.lookupRule(name)
+}
+
+func (r *RuleRegistry) lookupRule(name string) (Rule, bool) {
+	rule, ok := r.rules[name]
+	return rule, ok
+}
+
+// WithRules makes the rules of the registry referable by name from the expression.
+func WithRules(registry *RuleRegistry) ParseOption {
+	return func(c *parseConfig) {
+		c.rules = registry
+	}
+}
+
+// checkCycles reports an error if rule, together with the rules found by lookup,
+// references itself directly or through other rules.
+func checkCycles(rule Rule, lookup func(name string) (Rule, bool)) error {
+	visiting := map[string]bool{}
+
+	var visit func(r Rule, path []string) error
+	visit = func(r Rule, path []string) error {
+		path = append(path, r.Name())
+		if visiting[r.Name()] {
+			return fmt.Errorf("%w: %v", ErrCyclicDependency, path)
+		}
+		visiting[r.Name()] = true
+		defer delete(visiting, r.Name())
+
+		for _, name := range identifiers(r) {
+			if name == rule.Name() {
+				return fmt.Errorf("%w: %v", ErrCyclicDependency, append(path, name))
+			}
+			if ref, ok := lookup(name); ok {
+				if err := visit(ref, path); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+
+	return visit(rule, nil)
+}
+
+// identifiers returns the names of the elements and rules referenced by r.
+// Rules not created by Parse reference nothing.
+func identifiers(r Rule) []string {
+	pr, ok := r.(*rule)
+	if !ok {
+		return nil
+	}
+
+	seen := map[string]bool{}
+	var names []string
+	for _, token := range pr.r {
+		if isOperator(token) || isLiteral(token) || seen[token] {
+			continue
+		}
+		if _, _, ok := parseCall(token); ok {
+			continue
+		}
+		seen[token] = true
+		names = append(names, token)
+	}
+	return names
+}
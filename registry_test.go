@@ -0,0 +1,82 @@
+package rules
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestRuleReferences(t *testing.T) {
+	var economy = NewAttribute("economy")
+	var blacklisted = NewAttribute("blacklisted")
+
+	calls := 0
+	gold := NewComputedAttribute("gold", func(ctx RuleContext) (bool, error) {
+		calls++
+		return true, nil
+	})
+
+	registry, err := NewRuleRegistry(MustParse("suitable", "economy AND gold"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := MustParse("upgrade", "suitable AND NOT blacklisted AND (suitable OR gold)", WithRules(registry))
+
+	got, err := r.Evaluate(NewContext(economy(true), blacklisted(false), gold))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got {
+		t.Errorf("Evaluate() = %v, want true", got)
+	}
+	if calls != 1 {
+		t.Errorf("referenced rule dependencies evaluated %d times, want 1", calls)
+	}
+
+	if _, err := r.Evaluate(NewContext(economy(true))); !errors.Is(err, ErrMissingDataInContext) {
+		t.Errorf("Evaluate() error = %v, want %v", err, ErrMissingDataInContext)
+	}
+}
+
+func TestRuleRegistryRegister(t *testing.T) {
+	registry, err := NewRuleRegistry()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := registry.Register(MustParse("a", "b AND x", WithRules(registry))); err != nil {
+		t.Fatal(err)
+	}
+	if err := registry.Register(MustParse("a", "x")); !errors.Is(err, ErrDuplicateRule) {
+		t.Errorf("Register() error = %v, want %v", err, ErrDuplicateRule)
+	}
+	if err := registry.Register(MustParse("c", "NOT a", WithRules(registry))); err != nil {
+		t.Fatal(err)
+	}
+	if err := registry.Register(MustParse("b", "c OR y", WithRules(registry))); !errors.Is(err, ErrCyclicDependency) {
+		t.Errorf("Register() error = %v, want %v", err, ErrCyclicDependency)
+	}
+	if err := registry.Register(MustParse("d", "d OR y", WithRules(registry))); !errors.Is(err, ErrCyclicDependency) {
+		t.Errorf("Register() error = %v, want %v", err, ErrCyclicDependency)
+	}
+	if _, ok := registry.Rule("b"); ok {
+		t.Errorf("Rule(b) found a rule that failed to register")
+	}
+}
+
+func TestRuleSetReferences(t *testing.T) {
+	var economy = NewAttribute("economy")
+	var smart = NewAttribute("smart")
+
+	rs := NewRuleSet(
+		MustParse("suitable", "economy AND smart"),
+		MustParse("upgrade", "suitable AND economy"),
+	)
+
+	got, err := rs.Evaluate(NewContext(economy(true), smart(true)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got {
+		t.Errorf("Evaluate() = %v, want true", got)
+	}
+}
@@ -0,0 +1,93 @@
+package rules
+
+import (
+	"errors"
+	"time"
+)
+
+// RuleResult is the outcome of a single rule evaluated as part of a RuleSet.
+type RuleResult struct {
+	// Name is the name of the rule.
+	Name string
+	// Result is the value the rule evaluated to. It is false if the rule was
+	// skipped or its evaluation failed.
+	Result bool
+	// Err is the error returned by the evaluation of the rule, if any.
+	Err error
+	// Overridden is true if an override applied to the rule.
+	Overridden bool
+	// Forced is true if the override replaced the result of the rule instead of skipping it.
+	Forced bool
+	// Override is the override that applied to the rule, if any.
+	Override RuleOverride
+	// Inactive is true if the rule was skipped because it is outside its validity
+	// window, or does not have any of the tags the set is filtered by.
+	Inactive bool
+	// Duration is the time spent evaluating the rule.
+	Duration time.Duration
+}
+
+func (r RuleResult) skipped() bool {
+	return r.Inactive || (r.Overridden && !r.Forced)
+}
+
+// RuleSetResult holds the outcome of every rule of a RuleSet, in evaluation order.
+type RuleSetResult struct {
+	Results []RuleResult
+	// Match is the name of the first rule that evaluated to true, or empty if none did.
+	Match string
+
+	result bool
+}
+
+// Result returns the result of the set, combined by its Strategy.
+// It is false if the evaluation of any rule failed.
+func (r *RuleSetResult) Result() bool {
+	return r.result
+}
+
+// Passed returns the results of the rules that evaluated to true.
+func (r *RuleSetResult) Passed() []RuleResult {
+	return r.filter(func(res RuleResult) bool {
+		return !res.skipped() && res.Err == nil && res.Result
+	})
+}
+
+// Failed returns the results of the rules that evaluated to false.
+func (r *RuleSetResult) Failed() []RuleResult {
+	return r.filter(func(res RuleResult) bool {
+		return !res.skipped() && res.Err == nil && !res.Result
+	})
+}
+
+// Skipped returns the results of the rules that were skipped because of an override,
+// or because they were inactive.
+func (r *RuleSetResult) Skipped() []RuleResult {
+	return r.filter(RuleResult.skipped)
+}
+
+// Errored returns the results of the rules whose evaluation failed.
+func (r *RuleSetResult) Errored() []RuleResult {
+	return r.filter(func(res RuleResult) bool {
+		return res.Err != nil
+	})
+}
+
+// Err returns the errors of all the rules whose evaluation failed, joined together.
+func (r *RuleSetResult) Err() error {
+	var errs []error
+	for _, res := range r.Errored() {
+		errs = append(errs, res.Err)
+	}
+	return errors.Join(errs...)
+}
+
+func (r *RuleSetResult) filter(keep func(RuleResult) bool) []RuleResult {
+	var results []RuleResult
+	for _, res := range r.Results {
+		if keep(res) {
+			results = append(results, res)
+		}
+	}
+	return results
+}
@@ -7,187 +7,164 @@ import (
 )
 
 type rule struct {
-	name string
-	r    []string
+	name       string
+	r          []string
+	functions  *FunctionRegistry
+	references *RuleRegistry
+	metadata   Metadata
+
+	// comments holds the comments of the expression, keyed by the index of the
+	// token they precede. Comments after the last token are keyed by len(r).
+	comments map[int][]string
+
+	// source is the expression the rule was parsed from, and tokens the tokens
+	// of r with their positions in it.
+	source string
+	tokens []token
 }
 
 func (r *rule) Name() string {
 	return r.name
 }
 
+// Metadata returns the metadata attached to the rule when it was parsed.
+func (r *rule) Metadata() Metadata {
+	return r.metadata.clone()
+}
+
 func (r *rule) String() string {
-	st := stack.Stack[string]{}
-	for i := 0; i < len(r.r); i++ {
-		token := r.r[i]
-		if token == kNOT {
-			op1 := st.MustPop()
-			st.Push(token + " " + op1)
-			continue
-		}
-		if precedence[token] > 0 {
-			op2 := st.MustPop()
-			op1 := st.MustPop()
-
-			// Determine whether to add parentheses
-			needsParens := false
-			if i < len(r.r)-1 && precedence[r.r[i+1]] > precedence[token] {
-				needsParens = false
-			}
+	return r.tree().String()
+}
 
-			if needsParens {
-				st.Push("(" + op1 + " " + token + " " + op2 + ")")
-			} else {
-				st.Push(op1 + " " + token + " " + op2)
-			}
-		} else {
-			st.Push(token)
-		}
+func (r *rule) addComments(i int, comments []string) {
+	if r.comments == nil {
+		r.comments = make(map[int][]string)
 	}
-	return st.MustPop()
+	r.comments[i] = append(r.comments[i], comments...)
 }
 
 func (r *rule) Evaluate(ctx RuleContext) (bool, error) {
-	i := 0
+	out, err := r.evaluate(ctx)
+	if err != nil {
+		return false, err
+	}
+	a, ok := out.(Attribute)
+	if !ok {
+		return false, fmt.Errorf("%w: output %s is not an attribute", ErrInvalidRule, out.getName())
+	}
+	return a.getValue(), nil
+}
+
+// evaluate evaluates the expression and returns the element it results in,
+// which is an attribute for boolean expressions and a variable otherwise.
+func (r *rule) evaluate(ctx RuleContext) (RuleElement, error) {
+	ev := newEvaluation(ctx)
+	if r.references != nil {
+		ev.addResolver(r.references)
+	}
 
 	st := &stack.Stack[RuleElement]{}
-	for {
-		if i >= len(r.r) {
-			break
-		}
-		op := r.r[i]
-		switch op {
-		case kNOT:
-			s1, ok := st.Pop()
+	for _, token := range r.r {
+		args := make([]RuleElement, arity(token))
+		for i := len(args) - 1; i >= 0; i-- {
+			arg, ok := st.Pop()
 			if !ok {
-				return false, fmt.Errorf("%w: missing operand for NOT operator", ErrInvalidRule)
-			}
-			s1a, ok := s1.(Attribute)
-			if !ok {
-				return false, fmt.Errorf("%w: operand for NOT operator must be an attribute", ErrInvalidRule)
-			}
-
-			st.Push(s1a.not())
-			i++
-			continue
-		case kAND:
-			s1, s2, err := popTwoAttributes(st)
-			if err != nil {
-				return false, fmt.Errorf("%w: missing operand for AND operator", ErrInvalidRule)
+				return nil, fmt.Errorf("%w: missing operand for %s", ErrInvalidRule, token)
 			}
+			args[i] = arg
+		}
 
-			st.Push(s2.and(s1))
-		case kOR:
-			s1, s2, err := popTwoAttributes(st)
-			if err != nil {
-				return false, fmt.Errorf("%w: missing operand for OR operator", ErrInvalidRule)
-			}
-			st.Push(s2.or(s1))
-		case kXOR:
-			s1, s2, err := popTwoAttributes(st)
-			if err != nil {
-				return false, fmt.Errorf("%w: missing operand for XOR operator", ErrInvalidRule)
-			}
-			st.Push(s2.xor(s1))
-		case kEQ:
-			s1, s2, err := popTwoVariables(st)
-			if err != nil {
-				return false, fmt.Errorf("%w: missing operand for EQ operator", ErrInvalidRule)
-			}
-			st.Push(s2.equalTo(s1))
-		case kNEQ:
-			s1, s2, err := popTwoVariables(st)
-			if err != nil {
-				return false, fmt.Errorf("%w: missing operand for NEQ operator", ErrInvalidRule)
-			}
-			st.Push(s2.notEqualTo(s1))
-		case kGT:
-			s1, s2, err := popTwoVariables(st)
-			if err != nil {
-				return false, fmt.Errorf("%w: missing operand for GT operator", ErrInvalidRule)
-			}
-			st.Push(s2.greaterThan(s1))
-		case kLT:
-			s1, s2, err := popTwoVariables(st)
-			if err != nil {
-				return false, fmt.Errorf("%w: missing operand for LT operator", ErrInvalidRule)
-			}
-			st.Push(s2.lessThan(s1))
-		case kGTE:
-			s1, s2, err := popTwoVariables(st)
-			if err != nil {
-				return false, fmt.Errorf("%w: missing operand for GTE operator", ErrInvalidRule)
-			}
-			st.Push(s2.greaterThanOrEqualTo(s1))
-		case kLTE:
-			s1, s2, err := popTwoVariables(st)
-			if err != nil {
-				return false, fmt.Errorf("%w: missing operand for LTE operator", ErrInvalidRule)
-			}
-			st.Push(s2.lessThanOrEqualTo(s1))
-		default:
-			el, ok := ctx.findElement(op)
-			if !ok {
-				return false, fmt.Errorf("%w: %s", ErrMissingDataInContext, op)
-			}
-			switch v := el.(type) {
-			case Attribute:
-				st.Push(v)
-			case Variable:
-				st.Push(v)
-			}
+		el, err := r.apply(ev, token, args)
+		if err != nil {
+			return nil, err
 		}
-		i++
+		st.Push(el)
 	}
 
 	if out, ok := st.Pop(); ok {
-		return out.(Attribute).getValue(), nil
+		return out, nil
 	}
 
-	return false, fmt.Errorf("%w: no output attribute", ErrInvalidRule)
+	return nil, fmt.Errorf("%w: no output attribute", ErrInvalidRule)
 }
 
-func popTwoAttributes(st *stack.Stack[RuleElement]) (Attribute, Attribute, error) {
-	s1, ok := st.Pop()
-	if !ok {
-		return nil, nil, fmt.Errorf("%w: no output attribute", ErrInvalidRule)
-	}
-	s2, ok := st.Pop()
-	if !ok {
-		return nil, nil, fmt.Errorf("%w: no output attribute", ErrInvalidRule)
+// apply evaluates a single token of the expression, given the results of its operands.
+func (r *rule) apply(ev *evaluation, token string, args []RuleElement) (RuleElement, error) {
+	if isOperator(token) {
+		return applyOperator(token, args)
 	}
-
-	s1a, ok := s1.(Attribute)
-	if !ok {
-		return nil, nil, fmt.Errorf("%w, expected attribute, got %T", ErrInvalidRule, s1)
+	if name, _, ok := parseCall(token); ok {
+		fun, ok := r.functions.lookup(name)
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownFunction, name)
+		}
+		return fun.call(args)
 	}
-
-	s2a, ok := s2.(Attribute)
-	if !ok {
-		return nil, nil, fmt.Errorf("%w, expected attribute, got %T", ErrInvalidRule, s2)
+	if lit, ok := parseLiteral(token); ok {
+		return lit, nil
 	}
 
-	return s1a, s2a, nil
-}
-
-func popTwoVariables(st *stack.Stack[RuleElement]) (Variable, Variable, error) {
-	s1, ok := st.Pop()
-	if !ok {
-		return nil, nil, fmt.Errorf("%w: no output attribute", ErrInvalidRule)
+	el, err := ev.resolve(token)
+	if err != nil {
+		return nil, err
 	}
-	s2, ok := st.Pop()
-	if !ok {
-		return nil, nil, fmt.Errorf("%w: no output attribute", ErrInvalidRule)
+	switch el.(type) {
+	case Attribute, Variable:
+		return el, nil
+	default:
+		return nil, fmt.Errorf("%w: unsupported element %s of type %s", ErrInvalidRule, token, el.getType())
 	}
+}
 
-	s1a, ok := s1.(Variable)
-	if !ok {
-		return nil, nil, fmt.Errorf("%w: expected variable, got %T", ErrInvalidRule, s1)
+// applyOperator applies a logical or comparison operator to its operands.
+func applyOperator(op string, args []RuleElement) (RuleElement, error) {
+	switch op {
+	case kNOT:
+		a, ok := args[0].(Attribute)
+		if !ok {
+			return nil, fmt.Errorf("%w: operand for NOT operator must be an attribute", ErrInvalidRule)
+		}
+		return a.not(), nil
+	case kAND, kOR, kXOR:
+		a1, ok1 := args[0].(Attribute)
+		a2, ok2 := args[1].(Attribute)
+		if !ok1 || !ok2 {
+			return nil, fmt.Errorf("%w: operands for %s operator must be attributes", ErrInvalidRule, op)
+		}
+		switch op {
+		case kAND:
+			return a1.and(a2), nil
+		case kOR:
+			return a1.or(a2), nil
+		default:
+			return a1.xor(a2), nil
+		}
+	default:
+		v1, ok1 := args[0].(Variable)
+		v2, ok2 := args[1].(Variable)
+		if !ok1 || !ok2 {
+			return nil, fmt.Errorf("%w: operands for %s operator must be variables", ErrInvalidRule, op)
+		}
+		if _, ok := compareValues(v1.getValue(), v2.getValue()); !ok {
+			return nil, fmt.Errorf("%w: cannot compare %s with %s", ErrInvalidRule, v1.getName(), v2.getName())
+		}
+		return compare(op, v1, v2), nil
 	}
+}
 
-	s2a, ok := s2.(Variable)
-	if !ok {
-		return nil, nil, fmt.Errorf("%w: expected variable, got %T", ErrInvalidRule, s2)
+func compare(op string, v1, v2 Variable) Attribute {
+	switch op {
+	case kEQ:
+		return v1.equalTo(v2)
+	case kNEQ:
+		return v1.notEqualTo(v2)
+	case kGT:
+		return v1.greaterThan(v2)
+	case kLT:
+		return v1.lessThan(v2)
+	case kGTE:
+		return v1.greaterThanOrEqualTo(v2)
+	default:
+		return v1.lessThanOrEqualTo(v2)
 	}
-
-	return s1a, s2a, nil
 }
@@ -0,0 +1,382 @@
+package rules
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// elementTypes maps the types of elements in rule files to their descriptors.
+var elementTypes = map[string]func(name string) RuleElement{
+	"attribute": func(name string) RuleElement { return NewAttribute(name) },
+	"bool":      func(name string) RuleElement { return NewAttribute(name) },
+	"string":    func(name string) RuleElement { return NewVariable[string](name) },
+	"int":       func(name string) RuleElement { return NewVariable[int](name) },
+	"int64":     func(name string) RuleElement { return NewVariable[int64](name) },
+	"uint":      func(name string) RuleElement { return NewVariable[uint](name) },
+	"uint64":    func(name string) RuleElement { return NewVariable[uint64](name) },
+	"float":     func(name string) RuleElement { return NewVariable[float64](name) },
+	"float64":   func(name string) RuleElement { return NewVariable[float64](name) },
+}
+
+// fileStrategies maps the strategies of rule files to strategies of a RuleSet.
+var fileStrategies = map[string]func() Strategy{
+	"all":   All,
+	"any":   Any,
+	"none":  None,
+	"first": FirstMatch,
+}
+
+// RuleFile is the content of a rule file: a RuleSet built from the rules it
+// declares, and the schema of the context they are evaluated against.
+type RuleFile struct {
+	RuleSet RuleSet
+	Schema  *Schema
+	// Rules holds the declarations of the rules, in file order. Their descriptions,
+	// tags and other metadata are also attached to the rules of the set.
+	Rules []RuleDefinition
+}
+
+// ElementDefinition declares an element of a rule file.
+type ElementDefinition struct {
+	Name string `yaml:"name"`
+	// Type is attribute (or bool), string, int, int64, uint, uint64 or float (or float64).
+	Type string `yaml:"type"`
+	// Required elements must be present in contexts validated against the schema.
+	Required bool `yaml:"required"`
+}
+
+// RuleDefinition declares a rule of a rule file.
+type RuleDefinition struct {
+	Name        string   `yaml:"name"`
+	Expr        string   `yaml:"expr"`
+	Description string   `yaml:"description"`
+	Tags        []string `yaml:"tags"`
+	Priority    int      `yaml:"priority"`
+	Owner       string   `yaml:"owner"`
+	Version     string   `yaml:"version"`
+	// ValidFrom and ValidTo are dates, as YYYY-MM-DD or RFC 3339, bounding the
+	// validity window of the rule.
+	ValidFrom string            `yaml:"validFrom"`
+	ValidTo   string            `yaml:"validTo"`
+	Labels    map[string]string `yaml:"labels"`
+}
+
+// ruleKeys are the keys of a rule in a rule file.
+var ruleKeys = []string{"name", "expr", "description", "tags", "priority", "owner", "version", "validFrom", "validTo", "labels"}
+
+// OverrideDefinition declares an override of a rule file.
+type OverrideDefinition struct {
+	Rule string `yaml:"rule"`
+	// When is an expression the override is limited to.
+	When string `yaml:"when"`
+	// Until is a date, as YYYY-MM-DD or RFC 3339, the override expires at.
+	Until  string         `yaml:"until"`
+	Scope  map[string]any `yaml:"scope"`
+	Reason string         `yaml:"reason"`
+	Author string         `yaml:"author"`
+	// Result forces the result of the rule instead of skipping it.
+	Result *bool `yaml:"result"`
+}
+
+// LoadRules reads a rule file in YAML, or in JSON, which is a subset of it:
+//
+//	elements:
+//	  - name: age
+//	    type: int
+//	    required: true
+//	  - name: gold
+//	    type: attribute
+//	definitions: |
+//	  ADULT = 18
+//	strategy: all
+//	rules:
+//	  - name: adult
+//	    expr: age GTE ADULT
+//	    description: The passenger is an adult.
+//	    tags: [age]
+//	    owner: legal
+//	    validFrom: 2024-01-01
+//	overrides:
+//	  - rule: adult
+//	    when: gold
+//	    until: 2024-12-31
+//	    reason: Gold card holders are always treated as adults.
+//
+// Definitions hold constants and macros, as in ParseDefinitions. The strategy is
+// all, any, none or first. If the file declares elements, rules and conditions of
+// overrides are checked against them with CheckRule. Errors are reported together,
+// prefixed with the name of the file and the line they occur at.
+func LoadRules(r io.Reader, filename string, opts ...ParseOption) (*RuleFile, error) {
+	var doc yaml.Node
+	if err := yaml.NewDecoder(r).Decode(&doc); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("%s: %w: empty rule file", filename, ErrInvalidDefinition)
+		}
+		return nil, fmt.Errorf("%s: %w: %w", filename, ErrInvalidDefinition, err)
+	}
+
+	l := &fileLoader{file: filename, opts: opts}
+	file := l.load(doc.Content[0])
+	if err := errors.Join(l.errs...); err != nil {
+		return nil, err
+	}
+	return file, nil
+}
+
+// LoadRulesFile reads the rule file at path, in YAML or JSON.
+func LoadRulesFile(path string, opts ...ParseOption) (*RuleFile, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return LoadRules(f, path, opts...)
+}
+
+// fileLoader builds the content of a rule file, collecting the errors it finds.
+type fileLoader struct {
+	file string
+	opts []ParseOption
+	errs []error
+
+	// parsed holds the rules and conditions of overrides to check against the
+	// schema, with the nodes of their expressions.
+	parsed []parsedRule
+}
+
+type parsedRule struct {
+	node *yaml.Node
+	rule Rule
+}
+
+func (l *fileLoader) errorf(node *yaml.Node, format string, args ...any) {
+	l.errs = append(l.errs, fmt.Errorf("%s:%d: %w", l.file, node.Line, fmt.Errorf(format, args...)))
+}
+
+func (l *fileLoader) load(root *yaml.Node) *RuleFile {
+	file := &RuleFile{}
+	if !l.checkKeys(root, "elements", "definitions", "strategy", "rules", "overrides") {
+		return file
+	}
+
+	file.Schema, _ = NewSchema() // cannot fail without descriptors
+	l.loadElements(field(root, "elements"), file.Schema)
+	if node := field(root, "definitions"); node != nil {
+		l.loadDefinitions(node)
+	}
+
+	var opts []RuleSetOption
+	if node := field(root, "strategy"); node != nil {
+		strategy, ok := fileStrategies[node.Value]
+		if !ok {
+			l.errorf(node, "%w: unknown strategy %q", ErrInvalidDefinition, node.Value)
+		} else {
+			opts = append(opts, WithStrategy(strategy()))
+		}
+	}
+
+	var items []*yaml.Node
+	for _, item := range l.items(field(root, "rules"), ruleKeys...) {
+		var def RuleDefinition
+		if err := item.Decode(&def); err != nil {
+			l.errorf(item, "%w: %w", ErrInvalidDefinition, err)
+			continue
+		}
+		items = append(items, item)
+		file.Rules = append(file.Rules, def)
+		opts = append(opts, WithPriority(def.Name, def.Priority))
+	}
+
+	set, err := NewRuleSetWithOptions(nil, opts...)
+	if err != nil {
+		l.errorf(root, "%w", err)
+		return file
+	}
+	file.RuleSet = set
+
+	for i, def := range file.Rules {
+		l.loadRule(items[i], def, set)
+	}
+
+	for _, item := range l.items(field(root, "overrides"), "rule", "when", "until", "scope", "reason", "author", "result") {
+		l.loadOverride(item, set)
+	}
+
+	if len(file.Schema.Names()) > 0 {
+		for _, p := range l.parsed {
+			for _, err := range checkRule(p.rule, file.Schema, []ruleResolver{set.(ruleResolver)}) {
+				l.errorf(p.node, "%w", err)
+			}
+		}
+	}
+
+	return file
+}
+
+func (l *fileLoader) loadElements(node *yaml.Node, schema *Schema) {
+	for _, item := range l.items(node, "name", "type", "required") {
+		var def ElementDefinition
+		if err := item.Decode(&def); err != nil {
+			l.errorf(item, "%w: %w", ErrInvalidDefinition, err)
+			continue
+		}
+		descriptor, ok := elementTypes[def.Type]
+		if !ok {
+			l.errorf(valueOf(item, "type"), "%w: unknown type %q of %s", ErrInvalidDefinition, def.Type, def.Name)
+			continue
+		}
+		if err := schema.Declare(descriptor(def.Name)); err != nil {
+			l.errorf(item, "%w", err)
+			continue
+		}
+		schema.required[def.Name] = def.Required
+	}
+}
+
+func (l *fileLoader) loadDefinitions(node *yaml.Node) {
+	definitions, err := ParseDefinitions(node.Value)
+	if err != nil {
+		l.errorf(node, "%w", err)
+		return
+	}
+	l.opts = append(l.opts, WithDefinitions(definitions))
+}
+
+func (l *fileLoader) loadRule(item *yaml.Node, def RuleDefinition, set RuleSet) {
+	if def.Name == "" {
+		l.errorf(item, "%w: rule without a name", ErrInvalidDefinition)
+		return
+	}
+	m := Metadata{
+		Description: def.Description,
+		Tags:        def.Tags,
+		Owner:       def.Owner,
+		Version:     def.Version,
+		Labels:      def.Labels,
+	}
+	for key, t := range map[string]*time.Time{"validFrom": &m.ValidFrom, "validTo": &m.ValidTo} {
+		value := field(item, key)
+		if value == nil {
+			continue
+		}
+		var err error
+		if *t, err = parseDate(value.Value); err != nil {
+			l.errorf(value, "%w: rule %s: %w", ErrInvalidDefinition, def.Name, err)
+			return
+		}
+	}
+
+	r, err := Parse(def.Name, def.Expr, append([]ParseOption{WithMetadata(m)}, l.opts...)...)
+	if err != nil {
+		l.errorf(valueOf(item, "expr"), "rule %s: %w", def.Name, err)
+		return
+	}
+	if err := set.AddRule(r); err != nil {
+		l.errorf(item, "%w", err)
+		return
+	}
+	l.parsed = append(l.parsed, parsedRule{node: valueOf(item, "expr"), rule: r})
+}
+
+func (l *fileLoader) loadOverride(item *yaml.Node, set RuleSet) {
+	var def OverrideDefinition
+	if err := item.Decode(&def); err != nil {
+		l.errorf(item, "%w: %w", ErrInvalidDefinition, err)
+		return
+	}
+
+	var opts []OverrideOption
+	if def.When != "" {
+		when, err := Parse(def.Rule+".when", def.When, l.opts...)
+		if err != nil {
+			l.errorf(valueOf(item, "when"), "override of %s: %w", def.Rule, err)
+			return
+		}
+		opts = append(opts, OverrideWhen(when))
+		l.parsed = append(l.parsed, parsedRule{node: valueOf(item, "when"), rule: when})
+	}
+	if def.Until != "" {
+		until, err := parseDate(def.Until)
+		if err != nil {
+			l.errorf(valueOf(item, "until"), "%w: override of %s: %w", ErrInvalidDefinition, def.Rule, err)
+			return
+		}
+		opts = append(opts, OverrideUntil(until))
+	}
+	for name, value := range def.Scope {
+		opts = append(opts, OverrideScope(name, value))
+	}
+	if def.Reason != "" || def.Author != "" {
+		opts = append(opts, OverrideReason(def.Reason, def.Author))
+	}
+	if def.Result != nil {
+		opts = append(opts, OverrideResult(*def.Result))
+	}
+
+	set.AddOverride(NewOverride(def.Rule, opts...))
+}
+
+// items returns the items of a sequence of mappings, reporting the items that are
+// not mappings or have keys other than the given ones.
+func (l *fileLoader) items(node *yaml.Node, keys ...string) []*yaml.Node {
+	if node == nil {
+		return nil
+	}
+	if node.Kind != yaml.SequenceNode {
+		l.errorf(node, "%w: expected a list", ErrInvalidDefinition)
+		return nil
+	}
+
+	var items []*yaml.Node
+	for _, item := range node.Content {
+		if l.checkKeys(item, keys...) {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// checkKeys reports whether node is a mapping of the given keys.
+func (l *fileLoader) checkKeys(node *yaml.Node, keys ...string) bool {
+	if node.Kind != yaml.MappingNode {
+		l.errorf(node, "%w: expected a mapping of %s", ErrInvalidDefinition, strings.Join(keys, ", "))
+		return false
+	}
+	ok := true
+	for i := 0; i < len(node.Content); i += 2 {
+		if key := node.Content[i]; indexOf(keys, key.Value) < 0 {
+			l.errorf(key, "%w: unknown key %q", ErrInvalidDefinition, key.Value)
+			ok = false
+		}
+	}
+	return ok
+}
+
+// field returns the value of key in a mapping node, or nil.
+func field(node *yaml.Node, key string) *yaml.Node {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// valueOf returns the value of key in a mapping node, or the node itself if it has
+// no such key, for reporting errors at the most precise line.
+func valueOf(node *yaml.Node, key string) *yaml.Node {
+	if v := field(node, key); v != nil {
+		return v
+	}
+	return node
+}
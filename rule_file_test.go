@@ -0,0 +1,84 @@
+package rules
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLoadRulesFile(t *testing.T) {
+	file, err := LoadRulesFile("testdata/rules/upgrade.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if names := file.Schema.Names(); !reflect.DeepEqual(names, []string{"age", "cabin", "gold", "miles"}) {
+		t.Errorf("unexpected schema %v", names)
+	}
+	var names []string
+	for _, r := range file.RuleSet.Rules() {
+		names = append(names, r.Name())
+	}
+	if !reflect.DeepEqual(names, []string{"economy", "adult", "frequentFlyer"}) {
+		t.Errorf("unexpected rules %v", names)
+	}
+	if def := file.Rules[0]; def.Description != "The passenger is an adult." || !reflect.DeepEqual(def.Tags, []string{"age", "legal"}) {
+		t.Errorf("unexpected definition %+v", def)
+	}
+
+	m := MetadataOf(file.RuleSet.Rules()[1])
+	if m.Owner != "legal" || m.Version != "2" || m.Labels["jurisdiction"] != "eu" || m.ValidFrom.Year() != 2020 || !m.HasTag("legal") {
+		t.Errorf("unexpected metadata %+v", m)
+	}
+
+	ctx, err := file.Schema.Context(map[string]any{"age": 16, "gold": true, "cabin": "economy", "miles": 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := file.RuleSet.EvaluateAll(ctx)
+	if !result.Result() {
+		t.Errorf("expected the rules to pass, got %v", result.Results)
+	}
+	if r := result.Results[1]; r.Name != "adult" || !r.Overridden || r.Override == nil {
+		t.Errorf("expected adult to be overridden, got %+v", r)
+	}
+}
+
+func TestLoadRulesJSON(t *testing.T) {
+	file, err := LoadRulesFile("testdata/rules/upgrade.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, err := file.Schema.Context(map[string]any{"age": 16, "gold": true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := file.RuleSet.Evaluate(ctx); err != nil || !ok {
+		t.Errorf("expected any rule to pass, got %v (%v)", ok, err)
+	}
+}
+
+func TestLoadRulesErrors(t *testing.T) {
+	_, err := LoadRulesFile("testdata/rules/broken.yaml")
+	if !errors.Is(err, ErrInvalidDefinition) {
+		t.Fatalf("expected ErrInvalidDefinition, got %v", err)
+	}
+	for _, want := range []string{
+		`broken.yaml:3: invalid definition: unknown type "integer"`,
+		"broken.yaml:6: rule adult:",
+		`broken.yaml:9: invalid definition: unknown key "colour"`,
+		`broken.yaml:10: invalid definition: unknown strategy "most"`,
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected %q in %v", want, err)
+		}
+	}
+
+	sources := []string{"", "rules: 1", "- a\n- b", "rules:\n  - name: a\n    expr: [1"}
+	for _, src := range sources {
+		if _, err := LoadRules(strings.NewReader(src), "test.yaml"); !errors.Is(err, ErrInvalidDefinition) {
+			t.Errorf("%q: expected ErrInvalidDefinition, got %v", src, err)
+		}
+	}
+}
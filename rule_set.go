@@ -1,50 +1,311 @@
 package rules
 
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// DuplicatePolicy decides what a RuleSet does when a rule is added under a name
+// that is already taken.
+type DuplicatePolicy int
+
+const (
+	// DuplicateError rejects the rule with ErrDuplicateRule.
+	DuplicateError DuplicatePolicy = iota
+	// DuplicateReplace replaces the existing rule, keeping its position.
+	DuplicateReplace
+	// DuplicateKeepFirst keeps the existing rule and ignores the new one.
+	DuplicateKeepFirst
+)
+
+// RuleSetOption configures a RuleSet.
+type RuleSetOption func(*ruleSet)
+
+// WithDuplicatePolicy sets what happens when a rule is added under a name that is already taken.
+func WithDuplicatePolicy(policy DuplicatePolicy) RuleSetOption {
+	return func(r *ruleSet) {
+		r.duplicates = policy
+	}
+}
+
+// WithPriority sets the priority of the rule with the given name. Rules with a higher
+// priority are evaluated first; rules with equal priority in the order they were added.
+func WithPriority(name string, priority int) RuleSetOption {
+	return func(r *ruleSet) {
+		r.priorities[name] = priority
+	}
+}
+
+// WithTagFilter makes the set evaluate only the rules having at least one of the
+// given tags. The other rules are skipped.
+func WithTagFilter(tags ...string) RuleSetOption {
+	return func(r *ruleSet) {
+		r.tags = append(r.tags, tags...)
+	}
+}
+
 type ruleSet struct {
-	rules     map[string]Rule
-	overrides []RuleOverride
+	rules      []Rule
+	index      map[string]int
+	overrides  []RuleOverride
+	duplicates DuplicatePolicy
+	priorities map[string]int
+	strategy   Strategy
+	now        func() time.Time
+	tags       []string
 }
 
-func (r *ruleSet) AddRule(rule Rule) {
-	r.rules[rule.Name()] = rule
+func (r *ruleSet) AddRule(rule Rule) error {
+	if i, ok := r.index[rule.Name()]; ok {
+		switch r.duplicates {
+		case DuplicateReplace:
+			if err := checkCycles(rule, r.lookupRule); err != nil {
+				return err
+			}
+			r.rules[i] = rule
+			return nil
+		case DuplicateKeepFirst:
+			return nil
+		default:
+			return fmt.Errorf("%w: %s", ErrDuplicateRule, rule.Name())
+		}
+	}
+	if err := checkCycles(rule, r.lookupRule); err != nil {
+		return err
+	}
+
+	r.index[rule.Name()] = len(r.rules)
+	r.rules = append(r.rules, rule)
+	return nil
 }
 
 func (r *ruleSet) AddOverride(override RuleOverride) {
 	r.overrides = append(r.overrides, override)
 }
 
+// Rules returns the rules of the set in evaluation order.
+func (r *ruleSet) Rules() []Rule {
+	rules := make([]Rule, len(r.rules))
+	copy(rules, r.rules)
+	sort.SliceStable(rules, func(i, j int) bool {
+		return r.priorities[rules[i].Name()] > r.priorities[rules[j].Name()]
+	})
+	return rules
+}
+
 func (r *ruleSet) Evaluate(ctx RuleContext) (bool, error) {
-	for _, rule := range r.rules {
-		if r.isOverridden(rule) {
+	ev := newEvaluation(ctx)
+	ev.addResolver(r)
+
+	steps, participants := r.plan(ev)
+	c := r.strategy.start(participants)
+	for _, st := range steps {
+		if st.err != nil {
+			return false, st.err
+		}
+		if st.skipped() {
 			continue
 		}
 
-		result, err := rule.Evaluate(ctx)
-		if err != nil {
-			return false, err
+		result := st.forced
+		if st.override == nil {
+			value, err := ev.evaluateRule(st.rule)
+			if err != nil {
+				return false, err
+			}
+			result = value.getValue()
 		}
-		if !result {
-			return false, nil
+		if c.add(st.rule.Name(), result) {
+			break
 		}
 	}
-	return true, nil
+	return c.result(), nil
 }
 
-func (r *ruleSet) isOverridden(r2 Rule) bool {
-	for _, override := range r.overrides {
-		if override.Name() == r2.Name() {
+// EvaluateAll evaluates every rule of the set, without stopping at the first
+// failure, and returns the outcome of each of them.
+func (r *ruleSet) EvaluateAll(ctx RuleContext) *RuleSetResult {
+	ev := newEvaluation(ctx)
+	ev.addResolver(r)
+
+	steps, participants := r.plan(ev)
+	c := r.strategy.start(participants)
+	_, stopAtMatch := r.strategy.(firstMatch)
+
+	result := &RuleSetResult{Results: make([]RuleResult, 0, len(steps))}
+	failed := false
+	for _, st := range steps {
+		res := RuleResult{
+			Name:       st.rule.Name(),
+			Err:        st.err,
+			Overridden: st.override != nil,
+			Override:   st.override,
+			Inactive:   st.inactive,
+		}
+
+		switch {
+		case st.err != nil:
+			failed = true
+		case st.skipped():
+		case st.override != nil:
+			res.Forced = true
+			res.Result = st.forced
+			c.add(res.Name, res.Result)
+		default:
+			start := time.Now()
+			value, err := ev.evaluateRule(st.rule)
+			res.Duration = time.Since(start)
+			if err != nil {
+				res.Err = err
+				failed = true
+				break
+			}
+			res.Result = value.getValue()
+			c.add(res.Name, res.Result)
+		}
+		result.Results = append(result.Results, res)
+
+		if res.Result && result.Match == "" {
+			result.Match = res.Name
+			if stopAtMatch {
+				break
+			}
+		}
+	}
+	result.result = !failed && c.result()
+
+	return result
+}
+
+// step is a rule to evaluate along with the override applying to it, if any.
+type step struct {
+	rule     Rule
+	override RuleOverride
+	forced   bool
+	isForced bool
+	inactive bool
+	err      error
+}
+
+// skipped reports whether the rule is skipped because of an override, or because
+// it is inactive.
+func (s step) skipped() bool {
+	return s.inactive || (s.override != nil && !s.isForced)
+}
+
+// plan returns the rules of the set in evaluation order, with the overrides that
+// apply to them in ev, and the number of rules that take part in the result. The
+// results of overridden rules are cached in ev for the rules referencing them.
+func (r *ruleSet) plan(ev *evaluation) ([]step, int) {
+	now := r.now()
+	rules := r.Rules()
+
+	steps := make([]step, 0, len(rules))
+	participants := 0
+	for _, rule := range rules {
+		st := step{rule: rule, inactive: !r.active(rule, now)}
+		if st.inactive {
+			steps = append(steps, st)
+			continue
+		}
+		st.override, st.err = r.override(ev, rule, now)
+		if o, ok := st.override.(*Override); ok {
+			st.forced, st.isForced = o.Result()
+		}
+		if st.override != nil && st.err == nil {
+			// Rules referencing an overridden rule see the result of the override.
+			// A skipped rule is waived, so it counts as passed for them.
+			ev.results[rule.Name()] = attribute{name: rule.Name(), value: st.forced || !st.isForced}
+		}
+		if st.err == nil && !st.skipped() {
+			participants++
+		}
+		steps = append(steps, st)
+	}
+	return steps, participants
+}
+
+// active reports whether rule takes part in evaluations at now: it is within its
+// validity window and has one of the tags the set is filtered by.
+func (r *ruleSet) active(rule Rule, now time.Time) bool {
+	m := MetadataOf(rule)
+	if !m.EffectiveAt(now) {
+		return false
+	}
+	if len(r.tags) == 0 {
+		return true
+	}
+	for _, tag := range r.tags {
+		if m.HasTag(tag) {
 			return true
 		}
 	}
 	return false
 }
 
+func (r *ruleSet) lookupRule(name string) (Rule, bool) {
+	i, ok := r.index[name]
+	if !ok {
+		return nil, false
+	}
+	return r.rules[i], true
+}
+
+// override returns the first override of the rule that applies at now, if any.
+func (r *ruleSet) override(ev *evaluation, rule Rule, now time.Time) (RuleOverride, error) {
+	for _, override := range r.overrides {
+		if override.Name() != rule.Name() {
+			continue
+		}
+
+		o, ok := override.(*Override)
+		if !ok {
+			return override, nil
+		}
+		applies, err := o.applies(ev, now)
+		if err != nil {
+			return nil, fmt.Errorf("override of %s: %w", rule.Name(), err)
+		}
+		if applies {
+			return override, nil
+		}
+	}
+	return nil, nil
+}
+
+// NewRuleSet creates a rule set evaluating the rules in the given order. A rule added
+// under a name that is already taken replaces the existing one. It panics if rules
+// reference each other in a cycle; use NewRuleSetWithOptions to get an error instead.
 func NewRuleSet(rules ...Rule) RuleSet {
+	rs, _ := NewRuleSetWithOptions(nil, WithDuplicatePolicy(DuplicateReplace))
+	for _, rule := range rules {
+		if err := rs.AddRule(rule); err != nil {
+			panic(err)
+		}
+	}
+	return rs
+}
+
+// NewRuleSetWithOptions creates a rule set evaluating the rules in the given order,
+// configured with the given options.
+func NewRuleSetWithOptions(rules []Rule, opts ...RuleSetOption) (RuleSet, error) {
 	rs := &ruleSet{
-		rules: make(map[string]Rule),
+		index:      make(map[string]int),
+		priorities: make(map[string]int),
+		strategy:   All(),
+		now:        time.Now,
+	}
+	for _, opt := range opts {
+		opt(rs)
+	}
+	if err := rs.strategy.validate(); err != nil {
+		return nil, err
 	}
 	for _, rule := range rules {
-		rs.AddRule(rule)
+		if err := rs.AddRule(rule); err != nil {
+			return nil, err
+		}
 	}
-	return rs
+	return rs, nil
 }
@@ -0,0 +1,167 @@
+package rules
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestRuleSetOrder(t *testing.T) {
+	rs, err := NewRuleSetWithOptions(
+		[]Rule{MustParse("a", "A"), MustParse("b", "B"), MustParse("c", "C"), MustParse("d", "D")},
+		WithPriority("c", 10),
+		WithPriority("d", -1),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var names []string
+	for _, r := range rs.Rules() {
+		names = append(names, r.Name())
+	}
+	if got, want := names, []string{"c", "a", "b", "d"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Rules() = %v, want %v", got, want)
+	}
+
+	// All elements are missing, so the error always names the first rule in order.
+	for i := 0; i < 20; i++ {
+		_, err := rs.Evaluate(NewContext())
+		if err == nil || err.Error() != "evaluating c: missing data in context: C" {
+			t.Fatalf("Evaluate() error = %v, want the error of rule c", err)
+		}
+	}
+}
+
+func TestRuleSetDuplicates(t *testing.T) {
+	var A = NewAttribute("A")
+	first, second := MustParse("rule", "A"), MustParse("rule", "NOT A")
+
+	if _, err := NewRuleSetWithOptions([]Rule{first, second}); !errors.Is(err, ErrDuplicateRule) {
+		t.Errorf("NewRuleSetWithOptions() error = %v, want %v", err, ErrDuplicateRule)
+	}
+	if got, err := NewRuleSet(first, second).Evaluate(NewContext(A(true))); err != nil || got {
+		t.Errorf("NewRuleSet() Evaluate() = %v, %v, want the second rule to replace the first", got, err)
+	}
+
+	tests := []struct {
+		policy DuplicatePolicy
+		want   bool
+	}{
+		{policy: DuplicateReplace, want: false},
+		{policy: DuplicateKeepFirst, want: true},
+	}
+	for _, tt := range tests {
+		rs, err := NewRuleSetWithOptions([]Rule{first, second}, WithDuplicatePolicy(tt.policy))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n := len(rs.Rules()); n != 1 {
+			t.Errorf("policy %d: len(Rules()) = %d, want 1", tt.policy, n)
+		}
+		got, err := rs.Evaluate(NewContext(A(true)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("policy %d: Evaluate() = %v, want %v", tt.policy, got, tt.want)
+		}
+	}
+}
+
+func TestRuleSetCycles(t *testing.T) {
+	rs := NewRuleSet(MustParse("a", "b AND X"))
+	if err := rs.AddRule(MustParse("b", "NOT a")); !errors.Is(err, ErrCyclicDependency) {
+		t.Errorf("AddRule() error = %v, want %v", err, ErrCyclicDependency)
+	}
+
+	func() {
+		defer func() {
+			if err, _ := recover().(error); !errors.Is(err, ErrCyclicDependency) {
+				t.Errorf("NewRuleSet() panicked with %v, want %v", err, ErrCyclicDependency)
+			}
+		}()
+		NewRuleSet(MustParse("a", "b AND X"), MustParse("b", "NOT a"))
+	}()
+
+	var X = NewAttribute("X")
+	rs, err := NewRuleSetWithOptions(
+		[]Rule{MustParse("a", "b"), MustParse("b", "X")},
+		WithDuplicatePolicy(DuplicateReplace),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := rs.AddRule(MustParse("b", "a")); !errors.Is(err, ErrCyclicDependency) {
+		t.Errorf("AddRule() replacing b error = %v, want %v", err, ErrCyclicDependency)
+	}
+	if got, err := rs.Evaluate(NewContext(X(true))); err != nil || !got {
+		t.Errorf("Evaluate() = %v, %v, want the replaced rule to be kept", got, err)
+	}
+}
+
+func TestRuleSetElementNamedLikeRule(t *testing.T) {
+	var A = NewAttribute("A")
+	var X = NewAttribute("X")
+
+	// Rule other reads element A of the context, not the result of rule A.
+	rs := NewRuleSet(MustParse("A", "X"), MustParse("other", "A"))
+	result := rs.EvaluateAll(NewContext(A(true), X(false)))
+
+	got := make(map[string]bool)
+	for _, r := range result.Results {
+		if r.Err != nil {
+			t.Fatalf("rule %s: %v", r.Name, r.Err)
+		}
+		got[r.Name] = r.Result
+	}
+	if want := map[string]bool{"A": false, "other": true}; !reflect.DeepEqual(got, want) {
+		t.Errorf("results = %v, want %v", got, want)
+	}
+}
+
+func TestRuleSetEvaluateAll(t *testing.T) {
+	var A = NewAttribute("A")
+	var B = NewAttribute("B")
+
+	rs := NewRuleSet(
+		MustParse("a", "A"),
+		MustParse("b", "B"),
+		MustParse("notA", "NOT A"),
+		MustParse("c", "C"),
+		MustParse("skipped", "NOT B"),
+	)
+	rs.AddOverride(MustParse("skipped", "B"))
+
+	result := rs.EvaluateAll(NewContext(A(true), B(false)))
+
+	names := func(results []RuleResult) []string {
+		var names []string
+		for _, r := range results {
+			names = append(names, r.Name)
+		}
+		return names
+	}
+
+	if got := names(result.Results); !reflect.DeepEqual(got, []string{"a", "b", "notA", "c", "skipped"}) {
+		t.Errorf("Results = %v", got)
+	}
+	if got := names(result.Passed()); !reflect.DeepEqual(got, []string{"a"}) {
+		t.Errorf("Passed() = %v", got)
+	}
+	if got := names(result.Failed()); !reflect.DeepEqual(got, []string{"b", "notA"}) {
+		t.Errorf("Failed() = %v", got)
+	}
+	if got := names(result.Skipped()); !reflect.DeepEqual(got, []string{"skipped"}) {
+		t.Errorf("Skipped() = %v", got)
+	}
+	if got := names(result.Errored()); !reflect.DeepEqual(got, []string{"c"}) {
+		t.Errorf("Errored() = %v", got)
+	}
+	if !errors.Is(result.Err(), ErrMissingDataInContext) {
+		t.Errorf("Err() = %v, want %v", result.Err(), ErrMissingDataInContext)
+	}
+	if result.Result() {
+		t.Errorf("Result() = true, want false")
+	}
+}
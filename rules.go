@@ -20,6 +20,38 @@ var (
 	ErrEmptyExpression = errors.New("empty expression")
 	// ErrInvalidExpression is an error indicating that the rule expression is invalid.
 	ErrInvalidExpression = errors.New("invalid expression")
+	// ErrCyclicDependency is an error indicating that elements or rules depend on each other in a cycle.
+	ErrCyclicDependency = errors.New("cyclic dependency")
+	// ErrDuplicateRule is an error indicating that a rule with the same name already exists.
+	ErrDuplicateRule = errors.New("duplicate rule")
+	// ErrInvalidDefinition is an error indicating that a constant or macro definition is invalid.
+	ErrInvalidDefinition = errors.New("invalid definition")
+	// ErrInvalidAction is an error indicating that an action cannot be parsed.
+	ErrInvalidAction = errors.New("invalid action")
+	// ErrMaxIterations is an error indicating that an engine kept firing rules past its iteration limit.
+	ErrMaxIterations = errors.New("too many iterations")
+	// ErrUnknownFunction is an error indicating that a rule expression calls a function that is not registered.
+	ErrUnknownFunction = errors.New("unknown function")
+	// ErrInvalidFunction is an error indicating that a function cannot be registered.
+	ErrInvalidFunction = errors.New("invalid function")
+	// ErrInvalidTable is an error indicating that a decision table or one of its rows is invalid.
+	ErrInvalidTable = errors.New("invalid decision table")
+	// ErrHitPolicy is an error indicating that the rows matched by a decision table violate its hit policy.
+	ErrHitPolicy = errors.New("hit policy violation")
+	// ErrNoMatch is an error indicating that no row of a decision table matched.
+	ErrNoMatch = errors.New("no matching row")
+	// ErrSchemaMismatch is an error indicating that an element does not match its declaration in a schema.
+	ErrSchemaMismatch = errors.New("schema mismatch")
+	// ErrUnknownElement is an error indicating that a name is neither declared in a schema nor the name of a rule.
+	ErrUnknownElement = errors.New("unknown element")
+	// ErrTypeMismatch is an error indicating that an operand of an expression has the wrong type.
+	ErrTypeMismatch = errors.New("type mismatch")
+	// ErrExpressionTooLarge is an error indicating that rewriting an expression would make it too large.
+	ErrExpressionTooLarge = errors.New("expression too large")
+	// ErrUntranslatable is an error indicating that an expression cannot be translated to another language, such as SQL.
+	ErrUntranslatable = errors.New("untranslatable expression")
+	// ErrInvalidStrategy is an error indicating that a strategy of a rule set cannot be used, such as AtLeast(0).
+	ErrInvalidStrategy = errors.New("invalid strategy")
 )
 
 // RuleElement is an interface that represents a rule element, which can be an attribute, a variable, or any other element of a rule.
@@ -35,10 +67,21 @@ type Rule interface {
 	Evaluate(ctx RuleContext) (bool, error)
 }
 
+// RuleSet is an interface that represents a collection of rules evaluated together.
+// Rules are evaluated in a deterministic order: by priority, then in the order they were added.
+//
+// AddRule returns an error and Rules is part of the interface since rules can reference
+// each other by name. Implementations outside this package must provide both.
 type RuleSet interface {
-	AddRule(rule Rule)
+	// AddRule adds rule to the set. It fails with ErrCyclicDependency if the rule
+	// references itself through other rules of the set, and with ErrDuplicateRule
+	// if its name is taken and the duplicate policy rejects it.
+	AddRule(rule Rule) error
 	AddOverride(override RuleOverride)
+	// Rules returns the rules of the set in evaluation order.
+	Rules() []Rule
 	Evaluate(ctx RuleContext) (bool, error)
+	EvaluateAll(ctx RuleContext) *RuleSetResult
 }
 
 type RuleOverride interface {
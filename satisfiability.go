@@ -0,0 +1,481 @@
+package rules
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+
+	"github.com/IAmRadek/rules/internal/sat"
+)
+
+// Assignment holds values of elements by name: booleans for attributes, and
+// float64 or string values for variables.
+type Assignment map[string]any
+
+// Context returns a context holding the elements of the assignment.
+func (a Assignment) Context() RuleContext {
+	names := make([]string, 0, len(a))
+	for name := range a {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	elems := make([]RuleElement, 0, len(a))
+	for _, name := range names {
+		switch v := a[name].(type) {
+		case bool:
+			elems = append(elems, NewAttribute(name)(v))
+		case float64:
+			elems = append(elems, NewVariable[float64](name)(v))
+		case string:
+			elems = append(elems, NewVariable[string](name)(v))
+		}
+	}
+	return NewContext(elems...)
+}
+
+// IsSatisfiable reports whether r holds for some context. See FindSatisfyingAssignment
+// for how rules are analysed.
+func IsSatisfiable(r Rule) (bool, error) {
+	_, ok, err := FindSatisfyingAssignment(r)
+	return ok, err
+}
+
+// IsTautology reports whether r holds for every context that it can be evaluated
+// against. See FindSatisfyingAssignment for how rules are analysed.
+func IsTautology(r Rule) (bool, error) {
+	s, lits, err := analyse(nil, r)
+	if err != nil {
+		return false, err
+	}
+	_, ok := s.solve(lits[0].Not())
+	return !ok, nil
+}
+
+// FindSatisfyingAssignment looks for values of the elements of r for which r holds,
+// and reports whether there are any.
+//
+// The analysis works on the boolean skeleton of the rule, without evaluating it:
+// attributes are boolean variables, rules referenced by r are expanded, and the
+// comparisons of an element or a call with literals are solved exactly, by
+// splitting the values of the element into the intervals between the literals.
+// Other conditions, such as comparisons of two elements or calls of boolean
+// functions, are treated as independent of each other, so a rule relying on, for
+// example, x GT y AND y GT x never holding is reported as satisfiable, and the
+// assignment holds no values for them. Numbers are treated as real numbers.
+//
+// The assignment holds a value for each attribute and each element compared with
+// literals. When r has no other conditions, r holds for the context of the assignment.
+func FindSatisfyingAssignment(r Rule) (Assignment, bool, error) {
+	s, lits, err := analyse(nil, r)
+	if err != nil {
+		return nil, false, err
+	}
+	a, ok := s.solve(lits[0])
+	return a, ok, nil
+}
+
+// Equivalent reports whether r1 and r2 hold for the same contexts. If they do not,
+// it returns a counterexample: an assignment for which one of the rules holds and
+// the other does not. Rules are analysed as described for FindSatisfyingAssignment.
+func Equivalent(r1, r2 Rule) (bool, Assignment, error) {
+	s, lits, err := analyse(nil, r1, r2)
+	if err != nil {
+		return false, nil, err
+	}
+	a, ok := s.solve(s.solver.Xor(lits[0], lits[1]))
+	return !ok, a, nil
+}
+
+// Implies reports whether r2 holds for every context r1 holds for. If it does not,
+// it returns a counterexample: an assignment for which r1 holds and r2 does not.
+// Rules are analysed as described for FindSatisfyingAssignment.
+func Implies(r1, r2 Rule) (bool, Assignment, error) {
+	s, lits, err := analyse(nil, r1, r2)
+	if err != nil {
+		return false, nil, err
+	}
+	a, ok := s.solve(lits[0], lits[1].Not())
+	return !ok, a, nil
+}
+
+// analyse encodes rules into a skeleton, and returns the literals that are true
+// when each of the rules holds.
+func analyse(resolvers []ruleResolver, rules ...Rule) (*skeleton, []sat.Literal, error) {
+	s, err := newSkeleton(resolvers, rules...)
+	if err != nil {
+		return nil, nil, err
+	}
+	lits := make([]sat.Literal, len(rules))
+	for i, r := range rules {
+		if lits[i], err = s.encode(r); err != nil {
+			return nil, nil, err
+		}
+	}
+	return s, lits, nil
+}
+
+// skeleton encodes the boolean skeletons of rules into a formula. The rules of a
+// skeleton share its variables, so formulas relating several rules can be built.
+type skeleton struct {
+	solver    sat.Solver
+	resolvers []ruleResolver
+
+	// attributes holds the variables of attributes, and atoms those of conditions
+	// treated as independent, by name or expression.
+	attributes map[string]sat.Literal
+	atoms      map[string]sat.Literal
+	// domains holds the domains of the elements and calls compared with literals,
+	// by expression.
+	domains   map[string]*domain
+	expanding map[*rule]bool
+}
+
+// domain splits the values of an element or call compared with literals into
+// regions: the literals themselves and the intervals between them.
+type domain struct {
+	element bool
+	// kind is kindNumber or kindString, or kindUnknown if the element is compared
+	// with literals of both kinds, in which case its comparisons are conditions
+	// treated as independent.
+	kind   valueKind
+	values []any
+	// regions holds a variable for each region, of which exactly one is true.
+	// Region 2i+1 is values[i], and region 2i the values between values[i-1]
+	// and values[i].
+	regions []sat.Literal
+}
+
+// newSkeleton creates a skeleton for rules, resolving the rules they reference with
+// their own registries, then with resolvers.
+func newSkeleton(resolvers []ruleResolver, rules ...Rule) (*skeleton, error) {
+	s := &skeleton{
+		resolvers:  resolvers,
+		attributes: make(map[string]sat.Literal),
+		atoms:      make(map[string]sat.Literal),
+		domains:    make(map[string]*domain),
+		expanding:  make(map[*rule]bool),
+	}
+
+	visited := make(map[*rule]bool)
+	for _, r := range rules {
+		rr, ok := r.(*rule)
+		if !ok {
+			return nil, fmt.Errorf("%w: rule %s cannot be analysed", ErrInvalidRule, r.Name())
+		}
+		s.collect(rr, visited)
+	}
+	keys := make([]string, 0, len(s.domains))
+	for key := range s.domains {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		s.split(s.domains[key])
+	}
+	return s, nil
+}
+
+// collect adds the literals r and the rules it references compare elements with
+// to their domains.
+func (s *skeleton) collect(r *rule, visited map[*rule]bool) {
+	if visited[r] {
+		return
+	}
+	visited[r] = true
+
+	r.tree().walk(func(n *node) {
+		if len(n.children) == 0 {
+			if ref, ok := s.lookup(r, n.token); ok {
+				s.collect(ref, visited)
+			}
+			return
+		}
+		if !isComparison(n.token) {
+			return
+		}
+		for i, term := range n.children {
+			v, ok := literalValue(n.children[1-i])
+			if !ok || isLiteral(term.token) {
+				continue
+			}
+			key := term.String()
+			d, ok := s.domains[key]
+			if !ok {
+				d = &domain{element: len(term.children) == 0 && !isCallToken(term.token), kind: kindOf(reflect.TypeOf(v))}
+				s.domains[key] = d
+			}
+			if kindOf(reflect.TypeOf(v)) != d.kind {
+				d.kind = kindUnknown
+			}
+			d.values = append(d.values, v)
+		}
+	})
+}
+
+// split sorts the values of d and creates its regions, excluding the intervals
+// holding no values.
+func (s *skeleton) split(d *domain) {
+	if d.kind == kindUnknown {
+		return
+	}
+	sort.Slice(d.values, func(i, j int) bool {
+		c, _ := compareValues(d.values[i], d.values[j])
+		return c < 0
+	})
+	values := d.values[:0]
+	for _, v := range d.values {
+		if len(values) == 0 || !sameLiteralValue(values[len(values)-1], v) {
+			values = append(values, v)
+		}
+	}
+	d.values = values
+
+	d.regions = make([]sat.Literal, 2*len(values)+1)
+	for i := range d.regions {
+		d.regions[i] = s.solver.NewVar()
+		if _, ok := d.witness(i); !ok {
+			s.solver.AddClause(d.regions[i].Not())
+		}
+	}
+	s.solver.ExactlyOne(d.regions...)
+}
+
+// witness returns a value of region i of d, if it holds any.
+func (d *domain) witness(i int) (any, bool) {
+	if i%2 == 1 {
+		return d.values[i/2], true
+	}
+	var below, above any
+	if i > 0 {
+		below = d.values[i/2-1]
+	}
+	if i/2 < len(d.values) {
+		above = d.values[i/2]
+	}
+
+	if d.kind == kindNumber {
+		switch {
+		case below == nil:
+			return math.Floor(above.(float64)) - 1, true
+		case above == nil:
+			return math.Floor(below.(float64)) + 1, true
+		}
+		lo, hi := below.(float64), above.(float64)
+		if v := math.Floor(lo) + 1; v < hi {
+			return v, true
+		}
+		return lo + (hi-lo)/2, true
+	}
+
+	switch {
+	case below == nil:
+		return "", above.(string) != ""
+	case above == nil:
+		return below.(string) + "a", true
+	}
+	// Extending a string makes it greater, and the shortest extension is the smallest.
+	for _, v := range []string{below.(string) + "a", below.(string) + "\x00"} {
+		if v < above.(string) {
+			return v, true
+		}
+	}
+	return nil, false
+}
+
+// encode returns a literal that is true when r holds.
+func (s *skeleton) encode(r Rule) (sat.Literal, error) {
+	rr, ok := r.(*rule)
+	if !ok {
+		return 0, fmt.Errorf("%w: rule %s cannot be analysed", ErrInvalidRule, r.Name())
+	}
+	if s.expanding[rr] {
+		return 0, fmt.Errorf("%w: %s", ErrCyclicDependency, rr.name)
+	}
+	s.expanding[rr] = true
+	defer delete(s.expanding, rr)
+
+	return s.node(rr, rr.tree())
+}
+
+func (s *skeleton) node(r *rule, n *node) (sat.Literal, error) {
+	lits := make([]sat.Literal, len(n.children))
+	if n.token == kNOT || n.isLogical() {
+		for i, child := range n.children {
+			l, err := s.node(r, child)
+			if err != nil {
+				return 0, err
+			}
+			lits[i] = l
+		}
+	}
+
+	switch {
+	case n.token == kNOT:
+		return lits[0].Not(), nil
+	case n.token == kAND:
+		return s.solver.And(lits...), nil
+	case n.token == kOR:
+		return s.solver.Or(lits...), nil
+	case n.token == kXOR:
+		return s.solver.Xor(lits[0], lits[1]), nil
+	case isComparison(n.token):
+		return s.comparison(n), nil
+	case len(n.children) > 0 || isLiteral(n.token):
+		return s.atom(n.String()), nil
+	}
+
+	if ref, ok := s.lookup(r, n.token); ok {
+		return s.encode(ref)
+	}
+	l, ok := s.attributes[n.token]
+	if !ok {
+		l = s.solver.NewVar()
+		s.attributes[n.token] = l
+	}
+	return l, nil
+}
+
+// lookup returns the rule named name, if r references one.
+func (s *skeleton) lookup(r *rule, name string) (*rule, bool) {
+	ref, ok := r.lookupReference(name, s.resolvers)
+	if !ok {
+		return nil, false
+	}
+	rr, ok := ref.(*rule)
+	return rr, ok
+}
+
+func (s *skeleton) atom(key string) sat.Literal {
+	l, ok := s.atoms[key]
+	if !ok {
+		l = s.solver.NewVar()
+		s.atoms[key] = l
+	}
+	return l
+}
+
+// comparison returns a literal that is true when the comparison n holds.
+func (s *skeleton) comparison(n *node) sat.Literal {
+	left, right := n.children[0], n.children[1]
+	lv, lok := literalValue(left)
+	rv, rok := literalValue(right)
+	switch {
+	case lok && rok:
+		if _, ok := compareValues(lv, rv); !ok {
+			return s.solver.Constant(false)
+		}
+		l, _ := parseLiteral(left.token)
+		r, _ := parseLiteral(right.token)
+		return s.solver.Constant(compare(n.token, l, r).getValue())
+	case rok:
+		if d, ok := s.domain(left); ok {
+			return s.interval(d, n.token, rv)
+		}
+	case lok:
+		if d, ok := s.domain(right); ok {
+			return s.interval(d, mirrors[n.token], lv)
+		}
+	}
+
+	// Other comparisons are expressed with EQ and GT, so that a comparison and its
+	// negation share their variable.
+	l, r := left.String(), right.String()
+	switch n.token {
+	case kEQ, kNEQ:
+		if r < l {
+			l, r = r, l
+		}
+		a := s.atom(l + " " + kEQ + " " + r)
+		if n.token == kNEQ {
+			return a.Not()
+		}
+		return a
+	case kGT:
+		return s.atom(l + " " + kGT + " " + r)
+	case kLTE:
+		return s.atom(l + " " + kGT + " " + r).Not()
+	case kLT:
+		return s.atom(r + " " + kGT + " " + l)
+	default:
+		return s.atom(r + " " + kGT + " " + l).Not()
+	}
+}
+
+// domain returns the domain of the values of n, if they are split into regions.
+func (s *skeleton) domain(n *node) (*domain, bool) {
+	d, ok := s.domains[n.String()]
+	return d, ok && d.kind != kindUnknown
+}
+
+// interval returns a literal that is true when the value of d compares to v with op.
+func (s *skeleton) interval(d *domain, op string, v any) sat.Literal {
+	i := sort.Search(len(d.values), func(i int) bool {
+		c, _ := compareValues(d.values[i], v)
+		return c >= 0
+	})
+	point := 2*i + 1
+	switch op {
+	case kEQ:
+		return d.regions[point]
+	case kNEQ:
+		return d.regions[point].Not()
+	case kLT:
+		return s.solver.Or(d.regions[:point]...)
+	case kLTE:
+		return s.solver.Or(d.regions[:point+1]...)
+	case kGT:
+		return s.solver.Or(d.regions[point+1:]...)
+	default:
+		return s.solver.Or(d.regions[point:]...)
+	}
+}
+
+// solve looks for an assignment for which every literal of lits is true.
+func (s *skeleton) solve(lits ...sat.Literal) (Assignment, bool) {
+	model, ok := s.solver.Solve(lits...)
+	if !ok {
+		return nil, false
+	}
+	return s.assignment(model), true
+}
+
+// assignment decodes the values of attributes and elements from a model of the formula.
+func (s *skeleton) assignment(model []bool) Assignment {
+	a := make(Assignment)
+	for name, l := range s.attributes {
+		a[name] = holds(model, l)
+	}
+	for name, d := range s.domains {
+		if !d.element || d.kind == kindUnknown {
+			continue
+		}
+		for i, l := range d.regions {
+			if holds(model, l) {
+				a[name], _ = d.witness(i)
+				break
+			}
+		}
+	}
+	return a
+}
+
+// holds reports whether l is true in model.
+func holds(model []bool, l sat.Literal) bool {
+	return model[l.Var()] == (l > 0)
+}
+
+// literalValue returns the value of n if it is a literal.
+func literalValue(n *node) (any, bool) {
+	l, ok := parseLiteral(n.token)
+	if !ok {
+		return nil, false
+	}
+	return l.value, true
+}
+
+func sameLiteralValue(a, b any) bool {
+	c, ok := compareValues(a, b)
+	return ok && c == 0
+}
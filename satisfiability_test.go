@@ -0,0 +1,219 @@
+package rules
+
+import (
+	"errors"
+	"math/rand"
+	"testing"
+)
+
+func TestIsSatisfiable(t *testing.T) {
+	registry, err := NewRuleRegistry(
+		MustParse("adult", "age GTE 18"),
+		MustParse("senior", "age GTE 65 OR retired"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		expr      string
+		sat       bool
+		tautology bool
+	}{
+		{expr: "gold", sat: true},
+		{expr: "gold AND NOT gold", sat: false},
+		{expr: "gold OR NOT gold", sat: true, tautology: true},
+		{expr: "gold XOR gold", sat: false},
+		{expr: "(gold OR vip) AND NOT gold AND NOT vip", sat: false},
+		{expr: "age GT 18 AND age LT 18", sat: false},
+		{expr: "age GT 18 AND age LT 19", sat: true},
+		{expr: "age GTE 18 AND age LTE 18 AND age NEQ 18", sat: false},
+		{expr: "age GT 18 OR age LTE 18", sat: true, tautology: true},
+		{expr: "age GT 18 OR 18 GTE age", sat: true, tautology: true},
+		{expr: "age EQ 18 AND age EQ 21", sat: false},
+		{expr: "age LT 0 AND -5 LT age", sat: true},
+		{expr: "country EQ \"PL\" AND country EQ \"DE\"", sat: false},
+		{expr: "country GT \"a\" AND country LT \"b\"", sat: true},
+		{expr: "country LT \"\"", sat: false},
+		{expr: "country GT \"a\" AND country LT \"a\\x00\"", sat: false},
+		{expr: "LEN(country) GT 3 AND LEN(country) LT 2", sat: false},
+		{expr: "age GT limit AND age LTE limit", sat: false},
+		{expr: "age GT limit AND limit GT age", sat: true},
+		{expr: "1 LT 2", sat: true, tautology: true},
+		{expr: "adult AND age LT 18", sat: false},
+		{expr: "NOT adult AND age GT 30", sat: false},
+		{expr: "adult OR age LT 18", sat: true, tautology: true},
+		{expr: "senior AND NOT retired AND age LT 65", sat: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			r := MustParse("test", tt.expr, WithRules(registry))
+			sat, err := IsSatisfiable(r)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if sat != tt.sat {
+				t.Errorf("expected satisfiable %t, got %t", tt.sat, sat)
+			}
+			tautology, err := IsTautology(r)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tautology != tt.tautology {
+				t.Errorf("expected tautology %t, got %t", tt.tautology, tautology)
+			}
+		})
+	}
+}
+
+func TestFindSatisfyingAssignment(t *testing.T) {
+	r := MustParse("upgrade", `gold AND age GTE 18 AND age LT 65 AND country NEQ "PL" AND NOT (country GT "X")`)
+	a, ok, err := FindSatisfyingAssignment(r)
+	if err != nil || !ok {
+		t.Fatalf("expected an assignment, got %v, %v", ok, err)
+	}
+	for _, name := range []string{"gold", "age", "country"} {
+		if _, ok := a[name]; !ok {
+			t.Errorf("expected a value for %s in %v", name, a)
+		}
+	}
+	if got, err := r.Evaluate(a.Context()); err != nil || !got {
+		t.Errorf("expected %v to satisfy the rule, got %v, %v", a, got, err)
+	}
+
+	if _, ok, err := FindSatisfyingAssignment(MustParse("never", "age GT 1 AND age LT 1")); ok || err != nil {
+		t.Errorf("expected no assignment, got %v, %v", ok, err)
+	}
+	if _, err := IsSatisfiable(constantRule(true)); !errors.Is(err, ErrInvalidRule) {
+		t.Errorf("expected ErrInvalidRule, got %v", err)
+	}
+}
+
+// TestFindSatisfyingAssignmentRandom checks the assignments of random rules by
+// evaluating the rules, and compares the results with evaluations on sample contexts.
+func TestFindSatisfyingAssignmentRandom(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	atoms := []string{"a", "b", "x GT 1", "x LTE 3", "x EQ 2", "x NEQ 1.5", "3 LT x", `s GTE "m"`, `s EQ "a"`}
+	var expr func(depth int) string
+	expr = func(depth int) string {
+		if depth == 0 || rnd.Intn(3) == 0 {
+			return atoms[rnd.Intn(len(atoms))]
+		}
+		switch rnd.Intn(4) {
+		case 0:
+			return "NOT (" + expr(depth-1) + ")"
+		default:
+			op := []string{kAND, kOR, kXOR}[rnd.Intn(3)]
+			return "(" + expr(depth-1) + ") " + op + " (" + expr(depth-1) + ")"
+		}
+	}
+
+	var samples []Assignment
+	for _, a := range []bool{false, true} {
+		for _, b := range []bool{false, true} {
+			for _, x := range []float64{0, 1, 1.5, 2, 2.5, 3, 4} {
+				for _, s := range []string{"", "a", "b", "m", "z"} {
+					samples = append(samples, Assignment{"a": a, "b": b, "x": x, "s": s})
+				}
+			}
+		}
+	}
+
+	for i := 0; i < 300; i++ {
+		r := MustParse("random", expr(4))
+		a, ok, err := FindSatisfyingAssignment(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		full := Assignment{"a": false, "b": false, "x": 0.0, "s": ""}
+		for name, v := range a {
+			full[name] = v
+		}
+		if ok {
+			if got, err := r.Evaluate(full.Context()); err != nil || !got {
+				t.Fatalf("%s: expected %v to satisfy the rule, got %v, %v", r, full, got, err)
+			}
+			continue
+		}
+		for _, sample := range samples {
+			if got, _ := r.Evaluate(sample.Context()); got {
+				t.Fatalf("%s: reported unsatisfiable, but holds for %v", r, sample)
+			}
+		}
+	}
+}
+
+func TestEquivalent(t *testing.T) {
+	before := MustParse("suitableForUpgrade", "passengerIsEconomy AND (passengerIsGoldCardHolder OR passengerIsSilverCardHolder) AND miles GTE 1000")
+	tests := []struct {
+		expr string
+		want bool
+	}{
+		{expr: "(passengerIsEconomy AND passengerIsGoldCardHolder AND miles GTE 1000) OR (passengerIsEconomy AND passengerIsSilverCardHolder AND NOT (miles LT 1000))", want: true},
+		{expr: "NOT (NOT passengerIsEconomy OR (NOT passengerIsGoldCardHolder AND NOT passengerIsSilverCardHolder) OR 1000 GT miles)", want: true},
+		// AND and OR have the same precedence, so this is not the rule above without parentheses.
+		{expr: "passengerIsEconomy AND passengerIsGoldCardHolder AND miles GTE 1000 OR passengerIsEconomy AND passengerIsSilverCardHolder AND miles GTE 1000", want: false},
+		{expr: "passengerIsEconomy AND (passengerIsGoldCardHolder OR passengerIsSilverCardHolder) AND miles GT 1000", want: false},
+		{expr: "passengerIsEconomy AND passengerIsGoldCardHolder AND miles GTE 1000", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			after := MustParse("suitableForUpgrade", tt.expr)
+			ok, counterexample, err := Equivalent(before, after)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ok != tt.want {
+				t.Fatalf("expected %t, got %t", tt.want, ok)
+			}
+			if ok {
+				return
+			}
+			got1, err1 := before.Evaluate(counterexample.Context())
+			got2, err2 := after.Evaluate(counterexample.Context())
+			if err1 != nil || err2 != nil || got1 == got2 {
+				t.Errorf("expected the rules to differ for %v, got %v (%v) and %v (%v)", counterexample, got1, err1, got2, err2)
+			}
+		})
+	}
+}
+
+func TestImplies(t *testing.T) {
+	tests := []struct {
+		r1, r2 string
+		want   bool
+	}{
+		{r1: "gold AND age GTE 18", r2: "gold", want: true},
+		{r1: "age GT 30", r2: "age GTE 18", want: true},
+		{r1: "age GT 30 OR vip", r2: "age GTE 18", want: false},
+		{r1: "age GTE 18", r2: "age GT 30", want: false},
+		{r1: "age GT 30 AND age LT 20", r2: "gold", want: true},
+		{r1: "gold", r2: "vip OR NOT vip", want: true},
+		{r1: `country EQ "PL"`, r2: `country NEQ "DE"`, want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.r1+" => "+tt.r2, func(t *testing.T) {
+			r1, r2 := MustParse("r1", tt.r1), MustParse("r2", tt.r2)
+			ok, counterexample, err := Implies(r1, r2)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ok != tt.want {
+				t.Fatalf("expected %t, got %t", tt.want, ok)
+			}
+			if ok {
+				return
+			}
+			got1, _ := r1.Evaluate(counterexample.Context())
+			got2, _ := r2.Evaluate(counterexample.Context())
+			if !got1 || got2 {
+				t.Errorf("expected r1 and not r2 for %v, got %v and %v", counterexample, got1, got2)
+			}
+		})
+	}
+
+	if _, _, err := Implies(MustParse("r", "gold"), constantRule(true)); !errors.Is(err, ErrInvalidRule) {
+		t.Errorf("expected ErrInvalidRule, got %v", err)
+	}
+}
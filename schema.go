@@ -0,0 +1,215 @@
+package rules
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// Schema declares the elements a rule context is expected to hold, with their
+// types. Elements are declared with their descriptors, such as NewAttribute("gold")
+// or NewVariable[int]("age"), which the schema also uses to create elements from
+// values of other sources.
+type Schema struct {
+	elements map[string]reflect.Value
+	required map[string]bool
+}
+
+// NewSchema creates a schema declaring the elements of the given descriptors.
+func NewSchema(descriptors ...RuleElement) (*Schema, error) {
+	s := &Schema{
+		elements: make(map[string]reflect.Value),
+		required: make(map[string]bool),
+	}
+	for _, d := range descriptors {
+		if err := s.Declare(d); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// Declare adds the element of descriptor to the schema. Names must be unique.
+func (s *Schema) Declare(descriptor RuleElement) error {
+	fn := reflect.ValueOf(descriptor)
+	t := fn.Type()
+	if t.Kind() != reflect.Func || t.NumIn() != 1 || t.NumOut() != 1 || !t.Out(0).Implements(reflect.TypeOf((*RuleElement)(nil)).Elem()) {
+		return fmt.Errorf("%w: %s is not a descriptor", ErrInvalidDefinition, descriptor.getName())
+	}
+
+	name := descriptor.getName()
+	if !isName(name) {
+		return fmt.Errorf("%w: invalid name %q", ErrInvalidDefinition, name)
+	}
+	if _, ok := s.elements[name]; ok {
+		return fmt.Errorf("%w: %s is declared twice", ErrInvalidDefinition, name)
+	}
+	s.elements[name] = fn
+	return nil
+}
+
+// Require marks the named elements as required: contexts without them are invalid.
+// The elements must be declared.
+func (s *Schema) Require(names ...string) error {
+	for _, name := range names {
+		if !s.Has(name) {
+			return fmt.Errorf("%w: %s is not declared", ErrInvalidDefinition, name)
+		}
+		s.required[name] = true
+	}
+	return nil
+}
+
+// Names returns the names of the declared elements, sorted.
+func (s *Schema) Names() []string {
+	names := make([]string, 0, len(s.elements))
+	for name := range s.elements {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Has reports whether the schema declares an element with the given name.
+func (s *Schema) Has(name string) bool {
+	_, ok := s.elements[name]
+	return ok
+}
+
+// typeOf returns the type of the value of the named element, which is bool for attributes.
+func (s *Schema) typeOf(name string) (reflect.Type, bool) {
+	fn, ok := s.elements[name]
+	if !ok {
+		return nil, false
+	}
+	return fn.Type().In(0), true
+}
+
+// Element creates the named element holding value. Numbers are converted to the
+// declared type when no precision is lost.
+func (s *Schema) Element(name string, value any) (RuleElement, error) {
+	fn, ok := s.elements[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s is not declared", ErrUnknownElement, name)
+	}
+
+	t := fn.Type().In(0)
+	v, ok := convertValue(value, t)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s holds %s, got %T", ErrSchemaMismatch, name, t, value)
+	}
+	return fn.Call([]reflect.Value{v})[0].Interface().(RuleElement), nil
+}
+
+// Context creates a context holding the elements of values, by element name.
+// Like Validate, it reports every problem at once.
+func (s *Schema) Context(values map[string]any) (RuleContext, error) {
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var elems []RuleElement
+	var problems []error
+	for _, name := range names {
+		elem, err := s.Element(name, values[name])
+		if err != nil {
+			problems = append(problems, err)
+			continue
+		}
+		elems = append(elems, elem)
+	}
+	problems = append(problems, s.missing(func(name string) bool {
+		_, ok := values[name]
+		return ok
+	})...)
+
+	if err := errors.Join(problems...); err != nil {
+		return nil, err
+	}
+	return NewContext(elems...), nil
+}
+
+// Validate checks ctx against schema before it is evaluated. It reports every
+// element that is not declared, holds a value of another type than declared, or
+// is required but missing.
+func Validate(ctx RuleContext, schema *Schema) error {
+	var problems []error
+	for _, elem := range ctx.listElements() {
+		if err := schema.check(elem); err != nil {
+			problems = append(problems, err)
+		}
+	}
+	problems = append(problems, schema.missing(func(name string) bool {
+		_, ok := ctx.findElement(name)
+		return ok
+	})...)
+	return errors.Join(problems...)
+}
+
+// check verifies that elem is declared and holds a value of the declared type.
+// The values of computed elements are not known, so their types must be of the
+// same kind as the declared type.
+func (s *Schema) check(elem RuleElement) error {
+	name := elem.getName()
+	t, ok := s.typeOf(name)
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownElement, name)
+	}
+
+	var value any
+	switch e := elem.(type) {
+	case computedElement:
+		if kindOf(e.valueType()) != kindOf(t) {
+			return fmt.Errorf("%w: %s holds %s, got a computed %s", ErrSchemaMismatch, name, t, e.valueType())
+		}
+		return nil
+	case Attribute:
+		value = e.getValue()
+	case Variable:
+		value = e.getValue()
+	default:
+		return fmt.Errorf("%w: %s holds %s, got a %s", ErrSchemaMismatch, name, t, elem.getType())
+	}
+
+	if _, ok := convertValue(value, t); !ok {
+		return fmt.Errorf("%w: %s holds %s, got %T", ErrSchemaMismatch, name, t, value)
+	}
+	return nil
+}
+
+// missing returns a problem for each required element for which has reports false.
+func (s *Schema) missing(has func(name string) bool) []error {
+	var problems []error
+	for _, name := range s.Names() {
+		if s.required[name] && !has(name) {
+			problems = append(problems, fmt.Errorf("%w: %s", ErrMissingDataInContext, name))
+		}
+	}
+	return problems
+}
+
+// convertValue converts value to t. Numbers convert between numeric types when
+// no precision is lost.
+func convertValue(value any, t reflect.Type) (reflect.Value, bool) {
+	v := reflect.ValueOf(value)
+	if !v.IsValid() {
+		return reflect.Value{}, false
+	}
+	if v.Type() == t {
+		return v, true
+	}
+	if v.Kind() == t.Kind() && v.Type().ConvertibleTo(t) {
+		return v.Convert(t), true
+	}
+	if !isNumber(v.Kind()) || !isNumber(t.Kind()) {
+		return reflect.Value{}, false
+	}
+	converted := v.Convert(t)
+	if c, ok := compareValues(converted.Interface(), value); !ok || c != 0 {
+		return reflect.Value{}, false
+	}
+	return converted, true
+}
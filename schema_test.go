@@ -0,0 +1,104 @@
+package rules
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestSchemaElement(t *testing.T) {
+	schema, err := NewSchema(NewAttribute("gold"), NewVariable[int]("age"), NewVariable[string]("cabin"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := schema.Declare(NewAttribute("gold")); !errors.Is(err, ErrInvalidDefinition) {
+		t.Errorf("expected ErrInvalidDefinition for a duplicate, got %v", err)
+	}
+
+	elem, err := schema.Element("age", 42.0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, ok := elem.(Variable); !ok || v.getValue() != 42 {
+		t.Errorf("expected age to hold the int 42, got %#v", elem)
+	}
+
+	tests := []struct {
+		name  string
+		value any
+	}{
+		{"age", 4.2},
+		{"age", "42"},
+		{"gold", 1},
+	}
+	if _, err := schema.Element("unknown", true); !errors.Is(err, ErrUnknownElement) {
+		t.Errorf("expected ErrUnknownElement, got %v", err)
+	}
+	for _, tt := range tests {
+		if _, err := schema.Element(tt.name, tt.value); !errors.Is(err, ErrSchemaMismatch) {
+			t.Errorf("%s = %v: expected ErrSchemaMismatch, got %v", tt.name, tt.value, err)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	schema, err := NewSchema(NewAttribute("gold"), NewVariable[int]("age"), NewVariable[string]("country"), NewVariable[float64]("miles"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := schema.Require("age", "country"); err != nil {
+		t.Fatal(err)
+	}
+	if err := schema.Require("unknown"); !errors.Is(err, ErrInvalidDefinition) {
+		t.Errorf("expected ErrInvalidDefinition, got %v", err)
+	}
+
+	valid := NewContext(
+		NewVariable[int]("age")(30),
+		NewVariable[string]("country")("pl"),
+		NewVariable[int]("miles")(1000),
+		NewComputedAttribute("gold", func(RuleContext) (bool, error) { return true, nil }),
+	)
+	if err := Validate(valid, schema); err != nil {
+		t.Errorf("expected a valid context, got %v", err)
+	}
+
+	invalid := NewContext(
+		NewAttribute("age")(true),
+		NewVariable[float64]("miles")(12.5),
+		NewVariable[string]("gold")("yes"),
+		NewVariable[int]("nights")(3),
+		NewComputedVariable("country", func(RuleContext) (int, error) { return 1, nil }),
+	)
+	err = Validate(NewContext(NewVariable[float64]("age")(4.5)).MergeWith(invalid), schema)
+	for _, want := range []error{ErrSchemaMismatch, ErrUnknownElement} {
+		if !errors.Is(err, want) {
+			t.Errorf("expected %v, got %v", want, err)
+		}
+	}
+	for _, want := range []string{
+		"age holds int, got float64",
+		"gold holds bool, got string",
+		"unknown element: nights",
+		"country holds string, got a computed int",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected %q in %v", want, err)
+		}
+	}
+	if strings.Contains(err.Error(), "miles") {
+		t.Errorf("expected miles to be valid, got %v", err)
+	}
+
+	err = Validate(NewContext(NewVariable[int]("miles")(1)), schema)
+	if !errors.Is(err, ErrMissingDataInContext) || !strings.Contains(err.Error(), "age") || !strings.Contains(err.Error(), "country") {
+		t.Errorf("expected age and country to be missing, got %v", err)
+	}
+
+	_, err = schema.Context(map[string]any{"age": "30", "nights": 2})
+	for _, want := range []string{"age holds int, got string", "nights is not declared", "missing data in context: country"} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("expected %q in %v", want, err)
+		}
+	}
+}
@@ -0,0 +1,420 @@
+package rules
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// NormalFormOption configures ToCNF and ToDNF.
+type NormalFormOption func(*normalFormConfig)
+
+type normalFormConfig struct {
+	maxClauses int
+}
+
+// WithMaxClauses sets how many clauses a normal form may have. Converting to a
+// normal form can make an expression exponentially larger, and conversions
+// exceeding the limit fail with ErrExpressionTooLarge. The default is 1000.
+func WithMaxClauses(n int) NormalFormOption {
+	return func(c *normalFormConfig) {
+		c.maxClauses = n
+	}
+}
+
+// Simplify returns a rule equivalent to r with a simpler expression. Comparisons
+// of literals are folded, negations are pushed down to attributes and calls by De
+// Morgan's laws, negated comparisons are replaced by the opposite comparisons,
+// repeated operands of AND and OR are removed, and absorbed ones too, as in
+// a AND (a OR b), which is a. Operands keep their order, and comparisons are
+// written with the literal last. The rule keeps the name, registries and metadata
+// of r, but not its comments.
+//
+// The language has no boolean literals, so a rule that always holds becomes
+// 1 EQ 1, and a rule that never holds 1 NEQ 1.
+func Simplify(r Rule) (Rule, error) {
+	rr, ok := r.(*rule)
+	if !ok {
+		return nil, fmt.Errorf("%w: rule %s cannot be rewritten", ErrInvalidRule, r.Name())
+	}
+	return rr.derive(newBoolExpr(rr.tree(), false, false).simplify().node()), nil
+}
+
+// ToNNF returns r in negation normal form: an expression of AND and OR in which
+// NOT only applies to attributes and calls. Negated comparisons are replaced by
+// the opposite comparisons, and XOR is expanded into AND and OR.
+func ToNNF(r Rule) (Rule, error) {
+	rr, ok := r.(*rule)
+	if !ok {
+		return nil, fmt.Errorf("%w: rule %s cannot be rewritten", ErrInvalidRule, r.Name())
+	}
+	return rr.derive(newBoolExpr(rr.tree(), false, true).node()), nil
+}
+
+// ToCNF returns r in conjunctive normal form, simplified: an AND of clauses, each
+// an OR of conditions.
+func ToCNF(r Rule, opts ...NormalFormOption) (Rule, error) {
+	return toNormalForm(r, kAND, opts)
+}
+
+// ToDNF returns r in disjunctive normal form, simplified: an OR of clauses, each
+// an AND of conditions.
+func ToDNF(r Rule, opts ...NormalFormOption) (Rule, error) {
+	return toNormalForm(r, kOR, opts)
+}
+
+func toNormalForm(r Rule, outer string, opts []NormalFormOption) (Rule, error) {
+	cfg := normalFormConfig{maxClauses: 1000}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	rr, ok := r.(*rule)
+	if !ok {
+		return nil, fmt.Errorf("%w: rule %s cannot be rewritten", ErrInvalidRule, r.Name())
+	}
+
+	e := newBoolExpr(rr.tree(), false, true).simplify()
+	clauses, err := e.distribute(outer, cfg.maxClauses)
+	if err != nil {
+		return nil, fmt.Errorf("rule %s: %w", rr.name, err)
+	}
+
+	args := make([]*boolExpr, len(clauses))
+	for i, clause := range clauses {
+		args[i] = &boolExpr{op: duals[outer], args: clause}
+	}
+	return rr.derive((&boolExpr{op: outer, args: args}).simplify().node()), nil
+}
+
+// derive returns a rule with the expression of root, and the name, registries and
+// metadata of r.
+func (r *rule) derive(root *node) *rule {
+	d := &rule{
+		name:       r.name,
+		functions:  r.functions,
+		references: r.references,
+		metadata:   r.metadata.clone(),
+	}
+	root.walk(func(n *node) {
+		d.r = append(d.r, n.token)
+		d.tokens = append(d.tokens, token{text: n.token, pos: -1, grouped: n.grouped})
+	})
+	d.source = d.String()
+	return d
+}
+
+const (
+	opAtom  = "atom"
+	opTrue  = "true"
+	opFalse = "false"
+)
+
+var duals = map[string]string{
+	kAND:    kOR,
+	kOR:     kAND,
+	opTrue:  opFalse,
+	opFalse: opTrue,
+}
+
+// boolExpr is a boolean expression suited to rewriting: AND and OR take any number
+// of operands, and comparisons, attributes and calls are atoms.
+type boolExpr struct {
+	// op is kAND, kOR, kXOR, kNOT, opTrue, opFalse or opAtom.
+	op   string
+	args []*boolExpr
+	atom *node
+}
+
+// newBoolExpr converts the expression of n, negated if negate is true, pushing
+// negations down to atoms. XOR is expanded into AND and OR if expand is true.
+func newBoolExpr(n *node, negate, expand bool) *boolExpr {
+	switch {
+	case n.token == kNOT:
+		return newBoolExpr(n.children[0], !negate, expand)
+	case n.token == kAND || n.token == kOR:
+		op := n.token
+		if negate {
+			op = duals[op]
+		}
+		return &boolExpr{op: op, args: []*boolExpr{
+			newBoolExpr(n.children[0], negate, expand),
+			newBoolExpr(n.children[1], negate, expand),
+		}}
+	case n.token == kXOR && !expand:
+		return &boolExpr{op: kXOR, args: []*boolExpr{
+			newBoolExpr(n.children[0], negate, expand),
+			newBoolExpr(n.children[1], false, expand),
+		}}
+	case n.token == kXOR:
+		// a XOR b is (a AND NOT b) OR (NOT a AND b), and its negation is
+		// (a AND b) OR (NOT a AND NOT b).
+		a, b := n.children[0], n.children[1]
+		return &boolExpr{op: kOR, args: []*boolExpr{
+			{op: kAND, args: []*boolExpr{newBoolExpr(a, false, expand), newBoolExpr(b, !negate, expand)}},
+			{op: kAND, args: []*boolExpr{newBoolExpr(a, true, expand), newBoolExpr(b, negate, expand)}},
+		}}
+	case isComparison(n.token):
+		op, left, right := n.token, n.children[0], n.children[1]
+		if negate {
+			op = negations[op]
+		}
+		if isLiteral(left.token) && !isLiteral(right.token) {
+			op, left, right = mirrors[op], right, left
+		}
+		return &boolExpr{op: opAtom, atom: &node{token: op, children: []*node{left, right}, pos: -1}}
+	case negate:
+		return &boolExpr{op: kNOT, args: []*boolExpr{{op: opAtom, atom: n}}}
+	default:
+		return &boolExpr{op: opAtom, atom: n}
+	}
+}
+
+// not returns the negation of e, with negations pushed down to atoms.
+func (e *boolExpr) not() *boolExpr {
+	switch e.op {
+	case opTrue, opFalse:
+		return &boolExpr{op: duals[e.op]}
+	case kNOT:
+		return e.args[0]
+	case kAND, kOR:
+		args := make([]*boolExpr, len(e.args))
+		for i, a := range e.args {
+			args[i] = a.not()
+		}
+		return &boolExpr{op: duals[e.op], args: args}
+	case kXOR:
+		return &boolExpr{op: kXOR, args: []*boolExpr{e.args[0].not(), e.args[1]}}
+	default:
+		return newBoolExpr(e.atom, true, false)
+	}
+}
+
+// key identifies the expression: equivalent expressions whose operands are in a
+// different order have the same key.
+func (e *boolExpr) key() string {
+	switch e.op {
+	case opTrue, opFalse:
+		return e.op
+	case opAtom:
+		return e.atom.String()
+	case kNOT:
+		return kNOT + " " + e.args[0].key()
+	}
+	keys := make([]string, len(e.args))
+	for i, a := range e.args {
+		keys[i] = a.key()
+	}
+	sort.Strings(keys)
+	return e.op + "(" + strings.Join(keys, ", ") + ")"
+}
+
+// terms returns the keys of the operands of e if it is an op, or the key of e.
+func (e *boolExpr) terms(op string) map[string]bool {
+	if e.op != op {
+		return map[string]bool{e.key(): true}
+	}
+	terms := make(map[string]bool, len(e.args))
+	for _, a := range e.args {
+		terms[a.key()] = true
+	}
+	return terms
+}
+
+// simplify returns a simpler expression equivalent to e.
+func (e *boolExpr) simplify() *boolExpr {
+	switch e.op {
+	case opAtom:
+		return e.fold()
+	case kXOR:
+		a, b := e.args[0].simplify(), e.args[1].simplify()
+		switch {
+		case a.op == opTrue:
+			return b.not().simplify()
+		case a.op == opFalse:
+			return b
+		case b.op == opTrue:
+			return a.not().simplify()
+		case b.op == opFalse:
+			return a
+		case a.key() == b.key():
+			return &boolExpr{op: opFalse}
+		case a.key() == b.not().key():
+			return &boolExpr{op: opTrue}
+		}
+		return &boolExpr{op: kXOR, args: []*boolExpr{a, b}}
+	case kAND, kOR:
+		return e.simplifyChain()
+	default:
+		return e
+	}
+}
+
+// fold replaces a comparison of two literals by its result.
+func (e *boolExpr) fold() *boolExpr {
+	n := e.atom
+	if !isComparison(n.token) {
+		return e
+	}
+	l, lok := parseLiteral(n.children[0].token)
+	r, rok := parseLiteral(n.children[1].token)
+	if !lok || !rok {
+		return e
+	}
+	if _, ok := compareValues(l.value, r.value); !ok {
+		return e
+	}
+	if compare(n.token, l, r).getValue() {
+		return &boolExpr{op: opTrue}
+	}
+	return &boolExpr{op: opFalse}
+}
+
+// simplifyChain simplifies an AND or an OR.
+func (e *boolExpr) simplifyChain() *boolExpr {
+	identity, absorbing := opTrue, opFalse
+	if e.op == kOR {
+		identity, absorbing = opFalse, opTrue
+	}
+
+	var operands []*boolExpr
+	for _, a := range e.args {
+		a = a.simplify()
+		if a.op == e.op {
+			operands = append(operands, a.args...)
+			continue
+		}
+		operands = append(operands, a)
+	}
+
+	var args []*boolExpr
+	seen := make(map[string]bool)
+	for _, a := range operands {
+		switch {
+		case a.op == absorbing:
+			return a
+		case a.op == identity || seen[a.key()]:
+			continue
+		}
+		seen[a.key()] = true
+		args = append(args, a)
+	}
+	for _, a := range args {
+		if seen[a.not().key()] {
+			return &boolExpr{op: absorbing}
+		}
+	}
+
+	// a AND (a OR b) is a, and a OR (a AND b) is a: an operand whose terms include
+	// all the terms of another operand is absorbed by it.
+	kept := args[:0]
+	for i, a := range args {
+		if !absorbed(a, args, i, duals[e.op]) {
+			kept = append(kept, a)
+		}
+	}
+	args = kept
+
+	switch len(args) {
+	case 0:
+		return &boolExpr{op: identity}
+	case 1:
+		return args[0]
+	}
+	return &boolExpr{op: e.op, args: args}
+}
+
+// absorbed reports whether args[i], an operand of a chain of the dual of op, is
+// absorbed by another operand.
+func absorbed(a *boolExpr, args []*boolExpr, i int, op string) bool {
+	if a.op != op {
+		return false
+	}
+	terms := a.terms(op)
+	for j, b := range args {
+		if j == i {
+			continue
+		}
+		subset := true
+		for t := range b.terms(op) {
+			if !terms[t] {
+				subset = false
+				break
+			}
+		}
+		if subset {
+			return true
+		}
+	}
+	return false
+}
+
+// distribute converts e, whose negations apply to atoms only, into clauses of the
+// dual of outer joined by outer. It fails if there would be more than max clauses.
+func (e *boolExpr) distribute(outer string, max int) ([][]*boolExpr, error) {
+	switch e.op {
+	case outer:
+		var clauses [][]*boolExpr
+		for _, a := range e.args {
+			c, err := a.distribute(outer, max)
+			if err != nil {
+				return nil, err
+			}
+			clauses = append(clauses, c...)
+			if len(clauses) > max {
+				return nil, fmt.Errorf("%w: more than %d clauses", ErrExpressionTooLarge, max)
+			}
+		}
+		return clauses, nil
+	case duals[outer]:
+		clauses := [][]*boolExpr{nil}
+		for _, a := range e.args {
+			c, err := a.distribute(outer, max)
+			if err != nil {
+				return nil, err
+			}
+			if len(clauses)*len(c) > max {
+				return nil, fmt.Errorf("%w: more than %d clauses", ErrExpressionTooLarge, max)
+			}
+			product := make([][]*boolExpr, 0, len(clauses)*len(c))
+			for _, x := range clauses {
+				for _, y := range c {
+					product = append(product, append(append([]*boolExpr(nil), x...), y...))
+				}
+			}
+			clauses = product
+		}
+		return clauses, nil
+	default:
+		return [][]*boolExpr{{e}}, nil
+	}
+}
+
+// node converts e into an expression tree. Operands of AND and OR are joined from
+// the left, and mixes of them are grouped.
+func (e *boolExpr) node() *node {
+	switch e.op {
+	case opTrue, opFalse:
+		op := kEQ
+		if e.op == opFalse {
+			op = kNEQ
+		}
+		return &node{token: op, children: []*node{{token: "1", pos: -1}, {token: "1", pos: -1}}, pos: -1}
+	case opAtom:
+		return e.atom
+	}
+
+	group := func(n *node) *node {
+		if n.isLogical() && n.token != e.op {
+			n.grouped = true
+		}
+		return n
+	}
+	n := group(e.args[0].node())
+	for _, a := range e.args[1:] {
+		n = &node{token: e.op, children: []*node{n, group(a.node())}, pos: -1}
+	}
+	if e.op == kNOT {
+		n = &node{token: kNOT, children: []*node{n}, pos: -1}
+	}
+	return n
+}
@@ -0,0 +1,168 @@
+package rules
+
+import (
+	"errors"
+	"math/rand"
+	"testing"
+)
+
+func TestSimplify(t *testing.T) {
+	tests := []struct {
+		expr string
+		want string
+	}{
+		{expr: "A AND A", want: "A"},
+		{expr: "A AND B AND A", want: "A AND B"},
+		{expr: "NOT NOT A", want: "A"},
+		{expr: "NOT (A AND B)", want: "NOT A OR NOT B"},
+		{expr: "NOT (A OR NOT B)", want: "NOT A AND B"},
+		{expr: "NOT (X GT 1)", want: "X LTE 1"},
+		{expr: "18 LT age", want: "age GT 18"},
+		{expr: "A AND (A OR B)", want: "A"},
+		{expr: "(A OR B) AND C AND A", want: "C AND A"},
+		{expr: "A OR (A AND B) OR C", want: "A OR C"},
+		{expr: "(A AND B) OR (B AND A AND C)", want: "A AND B"},
+		{expr: "A AND NOT A", want: "1 NEQ 1"},
+		{expr: "X GT 1 OR X LTE 1", want: "1 EQ 1"},
+		{expr: "A OR 1 LT 2", want: "1 EQ 1"},
+		{expr: "A AND 1 LT 2", want: "A"},
+		{expr: `A AND "b" EQ "c"`, want: "1 NEQ 1"},
+		{expr: "A XOR A", want: "1 NEQ 1"},
+		{expr: "NOT (A XOR B)", want: "NOT A XOR B"},
+		{expr: "(A AND B) OR C", want: "(A AND B) OR C"},
+		{expr: "(A OR B) AND (C OR D)", want: "(A OR B) AND (C OR D)"},
+		{expr: "LOWER(C) EQ \"pl\" AND NOT (LOWER(C) EQ \"pl\")", want: "1 NEQ 1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			r := MustParse("rule", tt.expr, WithDescription("test"))
+			got, err := Simplify(r)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if s := got.(*rule).String(); s != tt.want {
+				t.Errorf("expected %s, got %s", tt.want, s)
+			}
+			if got.Name() != "rule" || MetadataOf(got).Description != "test" {
+				t.Errorf("expected the name and metadata of the rule, got %s, %v", got.Name(), MetadataOf(got))
+			}
+			if _, err := Parse("rule", got.(*rule).String()); err != nil {
+				t.Errorf("cannot parse %s: %v", got, err)
+			}
+		})
+	}
+
+	if _, err := Simplify(constantRule(true)); !errors.Is(err, ErrInvalidRule) {
+		t.Errorf("expected ErrInvalidRule, got %v", err)
+	}
+}
+
+func TestNormalForms(t *testing.T) {
+	tests := []struct {
+		expr string
+		nnf  string
+		cnf  string
+		dnf  string
+	}{
+		{
+			expr: "A AND (B OR C)",
+			nnf:  "A AND (B OR C)",
+			cnf:  "A AND (B OR C)",
+			dnf:  "(A AND B) OR (A AND C)",
+		},
+		{
+			expr: "NOT (A AND B) OR X GT 1",
+			nnf:  "NOT A OR NOT B OR X GT 1",
+			cnf:  "NOT A OR NOT B OR X GT 1",
+			dnf:  "NOT A OR NOT B OR X GT 1",
+		},
+		{
+			expr: "A XOR B",
+			nnf:  "(A AND NOT B) OR (NOT A AND B)",
+			cnf:  "(A OR B) AND (NOT B OR NOT A)",
+			dnf:  "(A AND NOT B) OR (NOT A AND B)",
+		},
+		{
+			expr: "(A OR B) AND (A OR C)",
+			nnf:  "(A OR B) AND (A OR C)",
+			cnf:  "(A OR B) AND (A OR C)",
+			dnf:  "A OR (B AND C)",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			r := MustParse("rule", tt.expr)
+			for _, form := range []struct {
+				name    string
+				convert func(Rule) (Rule, error)
+				want    string
+			}{
+				{"NNF", ToNNF, tt.nnf},
+				{"CNF", func(r Rule) (Rule, error) { return ToCNF(r) }, tt.cnf},
+				{"DNF", func(r Rule) (Rule, error) { return ToDNF(r) }, tt.dnf},
+			} {
+				got, err := form.convert(r)
+				if err != nil {
+					t.Fatalf("%s: %v", form.name, err)
+				}
+				if s := got.(*rule).String(); s != form.want {
+					t.Errorf("%s: expected %s, got %s", form.name, form.want, s)
+				}
+			}
+		})
+	}
+}
+
+func TestNormalFormTooLarge(t *testing.T) {
+	r := MustParse("rule", "(A AND B) OR (C AND D) OR (E AND F) OR (G AND H)")
+	if _, err := ToCNF(r, WithMaxClauses(8)); !errors.Is(err, ErrExpressionTooLarge) {
+		t.Errorf("expected ErrExpressionTooLarge, got %v", err)
+	}
+	got, err := ToCNF(r, WithMaxClauses(16))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok, _, err := Equivalent(r, got); err != nil || !ok {
+		t.Errorf("expected %s to be equivalent, got %v, %v", got, ok, err)
+	}
+}
+
+// TestRewritesRandom checks that rewritten random rules are equivalent to the originals.
+func TestRewritesRandom(t *testing.T) {
+	rnd := rand.New(rand.NewSource(2))
+	atoms := []string{"A", "B", "C", "NOT A", "X GT 1", "X LTE 1", "2 GT X", "X EQ 2", "1 LT 2"}
+	var expr func(depth int) string
+	expr = func(depth int) string {
+		if depth == 0 || rnd.Intn(4) == 0 {
+			return atoms[rnd.Intn(len(atoms))]
+		}
+		if rnd.Intn(4) == 0 {
+			return "NOT (" + expr(depth-1) + ")"
+		}
+		op := []string{kAND, kOR, kXOR}[rnd.Intn(3)]
+		return "(" + expr(depth-1) + ") " + op + " (" + expr(depth-1) + ")"
+	}
+
+	rewrites := map[string]func(Rule) (Rule, error){
+		"Simplify": Simplify,
+		"NNF":      ToNNF,
+		"CNF":      func(r Rule) (Rule, error) { return ToCNF(r) },
+		"DNF":      func(r Rule) (Rule, error) { return ToDNF(r) },
+	}
+	for i := 0; i < 200; i++ {
+		r := MustParse("random", expr(4))
+		for name, rewrite := range rewrites {
+			got, err := rewrite(r)
+			if errors.Is(err, ErrExpressionTooLarge) {
+				continue
+			}
+			if err != nil {
+				t.Fatalf("%s(%s): %v", name, r, err)
+			}
+			ok, counterexample, err := Equivalent(r, got)
+			if err != nil || !ok {
+				t.Fatalf("%s(%s) = %s, which differs for %v (%v)", name, r, got, counterexample, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,217 @@
+package rules
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// SQLDialect selects the syntax of the SQL produced by ToSQL.
+type SQLDialect int
+
+const (
+	Postgres SQLDialect = iota
+	MySQL
+	SQLite
+)
+
+func (d SQLDialect) String() string {
+	switch d {
+	case Postgres:
+		return "postgres"
+	case MySQL:
+		return "mysql"
+	case SQLite:
+		return "sqlite"
+	default:
+		return "unknown"
+	}
+}
+
+// sqlComparisons maps comparison operators to their SQL form.
+var sqlComparisons = map[string]string{
+	kEQ:  "=",
+	kNEQ: "<>",
+	kGT:  ">",
+	kLT:  "<",
+	kGTE: ">=",
+	kLTE: "<=",
+}
+
+// ToSQL translates r into a SQL predicate for a WHERE clause, and returns it with the
+// values of its placeholders. Elements are replaced with the column expressions
+// mapping gives for them, and names of rules r references through its registry are
+// replaced with the predicates of those rules. Literals become placeholders: $1, $2
+// and so on for Postgres, and ? for MySQL and SQLite.
+//
+// Elements without a column, functions other than the built-in ones and rules not
+// created by Parse cannot be translated, and ErrUntranslatable is returned for them.
+// It is also returned for TRIM, which strips all white space, where SQL TRIM strips
+// only spaces. The predicate follows the rules of the database for NULL values and string
+// collation, so rows with NULL columns do not match where evaluation would report
+// missing data.
+func ToSQL(r Rule, mapping map[string]string, dialect SQLDialect) (string, []any, error) {
+	if dialect < Postgres || dialect > SQLite {
+		return "", nil, fmt.Errorf("%w: unknown dialect %d", ErrUntranslatable, dialect)
+	}
+	w := &sqlWriter{mapping: mapping, dialect: dialect, expanding: make(map[string]bool)}
+	predicate, err := w.rule(r)
+	if err != nil {
+		return "", nil, err
+	}
+	return predicate, w.args, nil
+}
+
+// sqlWriter translates expression trees to SQL.
+type sqlWriter struct {
+	mapping   map[string]string
+	dialect   SQLDialect
+	args      []any
+	expanding map[string]bool
+}
+
+func (w *sqlWriter) rule(r Rule) (string, error) {
+	rr, ok := r.(*rule)
+	if !ok {
+		return "", fmt.Errorf("%w: rule %s", ErrUntranslatable, r.Name())
+	}
+	if w.expanding[rr.name] {
+		return "", fmt.Errorf("%w: %s", ErrCyclicDependency, rr.name)
+	}
+	w.expanding[rr.name] = true
+	defer delete(w.expanding, rr.name)
+
+	return w.node(rr, rr.tree())
+}
+
+func (w *sqlWriter) node(r *rule, n *node) (string, error) {
+	args := make([]string, len(n.children))
+	for i, child := range n.children {
+		arg, err := w.node(r, child)
+		if err != nil {
+			return "", err
+		}
+		if w.needsParens(n, child) {
+			arg = "(" + arg + ")"
+		}
+		args[i] = arg
+	}
+
+	switch {
+	case n.token == kAND || n.token == kOR:
+		return args[0] + " " + n.token + " " + args[1], nil
+	case n.token == kXOR:
+		if w.dialect == MySQL {
+			return args[0] + " XOR " + args[1], nil
+		}
+		// Boolean operands differ exactly when one of them holds.
+		return args[0] + " <> " + args[1], nil
+	case n.token == kNOT:
+		return "NOT " + args[0], nil
+	case isComparison(n.token):
+		return args[0] + " " + sqlComparisons[n.token] + " " + args[1], nil
+	}
+	if name, _, ok := parseCall(n.token); ok {
+		return w.call(name, args)
+	}
+	if lit, ok := parseLiteral(n.token); ok {
+		return w.placeholder(lit.value), nil
+	}
+	return w.element(r, n.token)
+}
+
+// needsParens reports whether the translation of child needs parentheses as an
+// operand of parent. Chains of the same operator are left alone, and so are
+// comparisons and negations under AND, OR and XOR, which bind looser than both.
+func (w *sqlWriter) needsParens(parent, child *node) bool {
+	emulatedXOR := parent.token == kXOR && w.dialect != MySQL
+	switch {
+	case child.token == kAND || child.token == kOR:
+		return child.token != parent.token
+	case child.token == kXOR:
+		return parent.token != kXOR || emulatedXOR
+	case child.token == kNOT || isComparison(child.token):
+		return !parent.isLogical() || emulatedXOR
+	default:
+		return false
+	}
+}
+
+// element translates the name of an element to its column expression, or to the
+// predicate of the rule it references.
+func (w *sqlWriter) element(r *rule, name string) (string, error) {
+	if column, ok := w.mapping[name]; ok {
+		if !isSQLIdentifier(column) {
+			column = "(" + column + ")"
+		}
+		return column, nil
+	}
+	if ref, ok := r.lookupReference(name, nil); ok {
+		predicate, err := w.rule(ref)
+		if err != nil {
+			return "", err
+		}
+		return "(" + predicate + ")", nil
+	}
+	return "", fmt.Errorf("%w: no column for %s", ErrUntranslatable, name)
+}
+
+// call translates a call to a built-in function.
+func (w *sqlWriter) call(name string, args []string) (string, error) {
+	if _, ok := builtins[name]; !ok {
+		return "", fmt.Errorf("%w: function %s", ErrUntranslatable, name)
+	}
+	switch name {
+	case "TRIM":
+		// SQL TRIM strips only spaces, while the built-in strips all white space.
+		return "", fmt.Errorf("%w: function %s", ErrUntranslatable, name)
+	case "LEN":
+		if w.dialect == SQLite {
+			return "LENGTH(" + args[0] + ")", nil
+		}
+		return "CHAR_LENGTH(" + args[0] + ")", nil
+	case "DAYS_BETWEEN":
+		switch w.dialect {
+		case Postgres:
+			return "EXTRACT(DAY FROM CAST(" + args[1] + " AS timestamptz) - CAST(" + args[0] + " AS timestamptz))", nil
+		case MySQL:
+			return "TIMESTAMPDIFF(DAY, " + args[0] + ", " + args[1] + ")", nil
+		default:
+			return "CAST(julianday(" + args[1] + ") - julianday(" + args[0] + ") AS INTEGER)", nil
+		}
+	default:
+		return name + "(" + strings.Join(args, ", ") + ")", nil
+	}
+}
+
+// placeholder adds value to the arguments and returns its placeholder. Whole
+// numbers are passed as integers, so they compare with integer columns exactly.
+func (w *sqlWriter) placeholder(value any) string {
+	if f, ok := value.(float64); ok && f == math.Trunc(f) && math.Abs(f) < 1<<53 {
+		value = int64(f)
+	}
+	w.args = append(w.args, value)
+	if w.dialect == Postgres {
+		return "$" + strconv.Itoa(len(w.args))
+	}
+	return "?"
+}
+
+// isSQLIdentifier reports whether s is a column name, possibly qualified with a table
+// name, that does not need parentheses.
+func isSQLIdentifier(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, c := range s {
+		if c != '_' && c != '.' && c != '"' && c != '`' && !isLetterOrDigit(c) {
+			return false
+		}
+	}
+	return true
+}
+
+func isLetterOrDigit(c rune) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
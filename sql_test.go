@@ -0,0 +1,137 @@
+package rules
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestToSQL(t *testing.T) {
+	registry, err := NewRuleRegistry(MustParse("adult", "age GTE 18"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	mapping := map[string]string{
+		"age":     "p.age",
+		"gold":    "p.gold",
+		"blocked": "p.blocked_at IS NOT NULL",
+		"country": "p.country",
+		"since":   "p.member_since",
+		"today":   "CURRENT_DATE",
+	}
+
+	tests := []struct {
+		name    string
+		expr    string
+		dialect SQLDialect
+		want    string
+		args    []any
+	}{
+		{
+			name: "comparisons",
+			expr: `age GT 18 AND LOWER(country) EQ "pl"`,
+			want: "p.age > $1 AND LOWER(p.country) = $2",
+			args: []any{int64(18), "pl"},
+		},
+		{
+			name:    "question mark placeholders",
+			expr:    `age LTE 65.5 OR country NEQ "de"`,
+			dialect: MySQL,
+			want:    "p.age <= ? OR p.country <> ?",
+			args:    []any{65.5, "de"},
+		},
+		{
+			name: "mixed operators",
+			expr: "(gold OR age GT 60) AND NOT blocked",
+			want: "(p.gold OR p.age > $1) AND NOT (p.blocked_at IS NOT NULL)",
+			args: []any{int64(60)},
+		},
+		{
+			name: "negated group",
+			expr: "NOT (gold AND blocked)",
+			want: "NOT (p.gold AND (p.blocked_at IS NOT NULL))",
+		},
+		{
+			name:    "xor",
+			expr:    "gold XOR age GT 60",
+			dialect: MySQL,
+			want:    "p.gold XOR p.age > ?",
+			args:    []any{int64(60)},
+		},
+		{
+			name:    "emulated xor",
+			expr:    "gold XOR age GT 60",
+			dialect: SQLite,
+			want:    "p.gold <> (p.age > ?)",
+			args:    []any{int64(60)},
+		},
+		{
+			name: "rule reference",
+			expr: "adult AND gold",
+			want: "(p.age >= $1) AND p.gold",
+			args: []any{int64(18)},
+		},
+		{
+			name:    "length",
+			expr:    "LEN(UPPER(country)) EQ 2",
+			dialect: SQLite,
+			want:    "LENGTH(UPPER(p.country)) = ?",
+			args:    []any{int64(2)},
+		},
+		{
+			name: "days between",
+			expr: "DAYS_BETWEEN(since, today) GT 365",
+			want: "EXTRACT(DAY FROM CAST(CURRENT_DATE AS timestamptz) - CAST(p.member_since AS timestamptz)) > $1",
+			args: []any{int64(365)},
+		},
+		{
+			name:    "days between in mysql",
+			expr:    "DAYS_BETWEEN(since, today) GT 365",
+			dialect: MySQL,
+			want:    "TIMESTAMPDIFF(DAY, p.member_since, CURRENT_DATE) > ?",
+			args:    []any{int64(365)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := MustParse("test", tt.expr, WithRules(registry))
+			got, args, err := ToSQL(r, mapping, tt.dialect)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+			if !reflect.DeepEqual(args, tt.args) {
+				t.Errorf("expected arguments %v, got %v", tt.args, args)
+			}
+		})
+	}
+}
+
+func TestToSQLErrors(t *testing.T) {
+	functions := NewFunctionRegistry()
+	if err := functions.Register("VIP", func(s string) bool { return s == "gold" }); err != nil {
+		t.Fatal(err)
+	}
+	mapping := map[string]string{"tier": "tier"}
+
+	tests := []struct {
+		name    string
+		rule    Rule
+		dialect SQLDialect
+	}{
+		{name: "unmapped element", rule: MustParse("test", "tier EQ 1 AND gold")},
+		{name: "custom function", rule: MustParse("test", "VIP(tier)", WithFunctions(functions))},
+		{name: "trim", rule: MustParse("test", `TRIM(tier) EQ "gold"`)},
+		{name: "rule not created by Parse", rule: constantRule(true)},
+		{name: "unknown dialect", rule: MustParse("test", "tier EQ 1"), dialect: SQLDialect(7)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := ToSQL(tt.rule, mapping, tt.dialect); !errors.Is(err, ErrUntranslatable) {
+				t.Errorf("expected ErrUntranslatable, got %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,189 @@
+package rules
+
+import "fmt"
+
+// Strategy decides how the results of the rules of a RuleSet combine into the
+// result of the set. Rules that are overridden take no part in it.
+//
+// The interface is sealed on purpose: its methods are unexported, so the
+// strategies are the ones of this package, and the way sets feed them results can
+// change without breaking callers. Weighted covers most custom combinations.
+type Strategy interface {
+	start(rules int) combiner
+	// validate reports why the strategy cannot be used, if it cannot.
+	validate() error
+}
+
+// combiner combines the results of the rules of a single evaluation.
+type combiner interface {
+	// add records the result of the next rule and reports whether the combined
+	// result is already decided, in which case the remaining rules can be skipped.
+	add(name string, result bool) bool
+	result() bool
+}
+
+// All is the default strategy: the set passes if every rule passes.
+func All() Strategy {
+	return all{}
+}
+
+type all struct{}
+
+func (all) start(int) combiner {
+	return &allCombiner{passed: true}
+}
+
+func (all) validate() error {
+	return nil
+}
+
+type allCombiner struct {
+	passed bool
+}
+
+func (c *allCombiner) add(_ string, result bool) bool {
+	c.passed = c.passed && result
+	return !c.passed
+}
+
+func (c *allCombiner) result() bool {
+	return c.passed
+}
+
+// Any makes the set pass if at least one rule passes.
+func Any() Strategy {
+	return AtLeast(1)
+}
+
+// None makes the set pass if no rule passes.
+func None() Strategy {
+	return none{}
+}
+
+type none struct{}
+
+func (none) start(int) combiner {
+	return &noneCombiner{passed: true}
+}
+
+func (none) validate() error {
+	return nil
+}
+
+type noneCombiner struct {
+	passed bool
+}
+
+func (c *noneCombiner) add(_ string, result bool) bool {
+	c.passed = c.passed && !result
+	return !c.passed
+}
+
+func (c *noneCombiner) result() bool {
+	return c.passed
+}
+
+// AtLeast makes the set pass if at least n rules pass, like a quorum of "3 out of 5 risk signals".
+// Rule sets reject it with ErrInvalidStrategy if n is less than 1.
+func AtLeast(n int) Strategy {
+	return atLeast{n: n}
+}
+
+type atLeast struct {
+	n int
+}
+
+func (s atLeast) validate() error {
+	if s.n < 1 {
+		return fmt.Errorf("%w: AtLeast needs at least 1 rule, got %d", ErrInvalidStrategy, s.n)
+	}
+	return nil
+}
+
+func (s atLeast) start(rules int) combiner {
+	return &atLeastCombiner{n: s.n, remaining: rules}
+}
+
+type atLeastCombiner struct {
+	n         int
+	passed    int
+	remaining int
+}
+
+func (c *atLeastCombiner) add(_ string, result bool) bool {
+	c.remaining--
+	if result {
+		c.passed++
+	}
+	return c.passed >= c.n || c.passed+c.remaining < c.n
+}
+
+func (c *atLeastCombiner) result() bool {
+	return c.passed >= c.n
+}
+
+// Weighted makes the set pass if the sum of the weights of the passing rules
+// reaches the threshold. Rules missing from weights have a weight of 1.
+// All the rules are always evaluated.
+func Weighted(weights map[string]float64, threshold float64) Strategy {
+	return weighted{weights: weights, threshold: threshold}
+}
+
+type weighted struct {
+	weights   map[string]float64
+	threshold float64
+}
+
+func (s weighted) start(int) combiner {
+	return &weightedCombiner{weighted: s}
+}
+
+func (weighted) validate() error {
+	return nil
+}
+
+type weightedCombiner struct {
+	weighted
+	score float64
+}
+
+func (c *weightedCombiner) add(name string, result bool) bool {
+	if !result {
+		return false
+	}
+	if w, ok := c.weights[name]; ok {
+		c.score += w
+	} else {
+		c.score++
+	}
+	return false
+}
+
+func (c *weightedCombiner) result() bool {
+	return c.score >= c.threshold
+}
+
+// FirstMatch makes the set pass if one of the rules passes, checking them in
+// evaluation order. Unlike Any, EvaluateAll also stops at the first passing rule,
+// and the rules after it are left out of the result.
+func FirstMatch() Strategy {
+	return firstMatch{}
+}
+
+type firstMatch struct{}
+
+func (firstMatch) start(rules int) combiner {
+	return AtLeast(1).start(rules)
+}
+
+func (firstMatch) validate() error {
+	return nil
+}
+
+// WithStrategy sets how the results of the rules combine into the result of the set.
+// NewRuleSetWithOptions fails with ErrInvalidStrategy if the strategy cannot be used.
+func WithStrategy(strategy Strategy) RuleSetOption {
+	return func(r *ruleSet) {
+		r.strategy = strategy
+	}
+}
@@ -0,0 +1,110 @@
+package rules
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestStrategies(t *testing.T) {
+	var A = NewAttribute("A")
+	var B = NewAttribute("B")
+	var C = NewAttribute("C")
+
+	rules := []Rule{MustParse("a", "A"), MustParse("b", "B"), MustParse("c", "C")}
+
+	tests := []struct {
+		name     string
+		strategy Strategy
+		ctx      RuleContext
+		want     bool
+	}{
+		{name: "all", strategy: All(), ctx: NewContext(A(true), B(true), C(true)), want: true},
+		{name: "all failing", strategy: All(), ctx: NewContext(A(true), B(false), C(true)), want: false},
+		{name: "any", strategy: Any(), ctx: NewContext(A(false), B(true), C(false)), want: true},
+		{name: "any failing", strategy: Any(), ctx: NewContext(A(false), B(false), C(false)), want: false},
+		{name: "none", strategy: None(), ctx: NewContext(A(false), B(false), C(false)), want: true},
+		{name: "none failing", strategy: None(), ctx: NewContext(A(false), B(false), C(true)), want: false},
+		{name: "at least 2", strategy: AtLeast(2), ctx: NewContext(A(true), B(false), C(true)), want: true},
+		{name: "at least 2 failing", strategy: AtLeast(2), ctx: NewContext(A(true), B(false), C(false)), want: false},
+		{
+			name:     "weighted",
+			strategy: Weighted(map[string]float64{"a": 0.5, "b": 2}, 2.5),
+			ctx:      NewContext(A(true), B(true), C(false)),
+			want:     true,
+		},
+		{
+			name:     "weighted failing",
+			strategy: Weighted(map[string]float64{"a": 0.5, "b": 2}, 2.5),
+			ctx:      NewContext(A(true), B(false), C(true)),
+			want:     false,
+		},
+		{name: "first match", strategy: FirstMatch(), ctx: NewContext(A(false), B(true), C(true)), want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rs, err := NewRuleSetWithOptions(rules, WithStrategy(tt.strategy))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := rs.Evaluate(tt.ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("Evaluate() = %v, want %v", got, tt.want)
+			}
+			if all := rs.EvaluateAll(tt.ctx).Result(); all != tt.want {
+				t.Errorf("EvaluateAll().Result() = %v, want %v", all, tt.want)
+			}
+		})
+	}
+}
+
+func TestStrategyShortCircuit(t *testing.T) {
+	var A = NewAttribute("A")
+
+	// Rule b can't be evaluated, so it must not be reached.
+	rules := []Rule{MustParse("a", "A"), MustParse("b", "B")}
+
+	tests := []struct {
+		name     string
+		strategy Strategy
+		value    bool
+	}{
+		{name: "all", strategy: All(), value: false},
+		{name: "any", strategy: Any(), value: true},
+		{name: "none", strategy: None(), value: true},
+		{name: "at least 2", strategy: AtLeast(2), value: false},
+		{name: "first match", strategy: FirstMatch(), value: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rs, err := NewRuleSetWithOptions(rules, WithStrategy(tt.strategy))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := rs.Evaluate(NewContext(A(tt.value))); err != nil {
+				t.Errorf("Evaluate() error = %v", err)
+			}
+		})
+	}
+
+	rs, err := NewRuleSetWithOptions(rules, WithStrategy(FirstMatch()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := rs.EvaluateAll(NewContext(A(true)))
+	if result.Match != "a" || len(result.Results) != 1 || result.Err() != nil {
+		t.Errorf("EvaluateAll() = %+v, want a single match of a", result)
+	}
+}
+
+func TestAtLeastInvalid(t *testing.T) {
+	for _, n := range []int{0, -1} {
+		_, err := NewRuleSetWithOptions([]Rule{MustParse("a", "A")}, WithStrategy(AtLeast(n)))
+		if !errors.Is(err, ErrInvalidStrategy) {
+			t.Errorf("NewRuleSetWithOptions() with AtLeast(%d) error = %v, want %v", n, err, ErrInvalidStrategy)
+		}
+	}
+}
@@ -36,9 +36,21 @@ func TestStringer(t *testing.T) {
 		{
 			rule: "A AND B AND C LT D",
 		},
+		{
+			rule: `LOWER(C) EQ "pl" AND ABS(D) LT 4.5`,
+		},
+		{
+			rule: "DAYS_BETWEEN(C, D) GT -1",
+		},
+		{
+			rule: "A AND (B OR C) AND NOT (C EQ D)",
+		},
 		{
 			rule: "A AND B AND C EQ D AND E EQ F",
 		},
+		{
+			rule: "(A OR B) AND C XOR D",
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.rule, func(t *testing.T) {
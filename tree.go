@@ -0,0 +1,179 @@
+package rules
+
+import (
+	"strings"
+
+	"github.com/IAmRadek/rules/internal/utils/stack"
+)
+
+// node is a node of the expression tree of a rule, built from its reverse polish notation.
+type node struct {
+	token    string
+	comments []string
+	children []*node
+
+	// pos is the offset of the token in the source of the rule, or -1 if unknown.
+	pos int
+	// parens is the number of parentheses written around the expression of the
+	// node, parenPos the offset of the outermost one, and grouped is true if the
+	// expression is parenthesised in any way, including by a macro.
+	parens   int
+	parenPos int
+	grouped  bool
+}
+
+// tree builds the expression tree of the rule, with the positions of its tokens
+// if they are known.
+func (r *rule) tree() *node {
+	root := buildTree(r.r, r.comments)
+	if len(r.tokens) == len(r.r) {
+		i := 0
+		root.walk(func(n *node) {
+			t := r.tokens[i]
+			n.pos, n.parens, n.parenPos, n.grouped = t.pos, t.parens, t.parenPos, t.grouped
+			i++
+		})
+	}
+	return root
+}
+
+// walk calls fn for n and its descendants in post-order, which is the order of
+// the tokens in reverse polish notation.
+func (n *node) walk(fn func(*node)) {
+	for _, child := range n.children {
+		child.walk(fn)
+	}
+	fn(n)
+}
+
+// buildTree builds an expression tree from a valid expression in reverse polish notation.
+// Comments are keyed by the index of the token they precede.
+func buildTree(rpn []string, comments map[int][]string) *node {
+	st := stack.Stack[*node]{}
+	for i, token := range rpn {
+		n := &node{token: token, comments: comments[i], pos: -1}
+		n.children = make([]*node, arity(token))
+		for j := len(n.children) - 1; j >= 0; j-- {
+			n.children[j] = st.MustPop()
+		}
+		st.Push(n)
+	}
+	return st.MustPop()
+}
+
+func (n *node) isBinary() bool {
+	return isOperator(n.token) && n.token != kNOT
+}
+
+func (n *node) isLogical() bool {
+	return n.token == kAND || n.token == kOR || n.token == kXOR
+}
+
+// start returns the node whose token is written first when n is printed.
+func (n *node) start() *node {
+	for n.isBinary() {
+		n = n.children[0]
+	}
+	return n
+}
+
+// needsParens reports whether child must be parenthesised when printed as the
+// i-th operand of n. Operators of equal precedence associate to the left, but
+// the parentheses grouping a mix of AND, OR and XOR are kept for readers.
+func (n *node) needsParens(i int) bool {
+	child := n.children[i]
+	if !child.isBinary() {
+		return false
+	}
+	switch {
+	case n.token == kNOT:
+		return true
+	case n.isLogical() && child.isLogical() && child.token != n.token && child.grouped:
+		return true
+	case n.isBinary():
+		return precedence[child.token] < precedence[n.token] ||
+			precedence[child.token] == precedence[n.token] && i > 0
+	default:
+		return false
+	}
+}
+
+func (n *node) String() string {
+	b := strings.Builder{}
+	n.write(&b, nil)
+	return b.String()
+}
+
+// write prints n on a single line. If printed is not nil, comments of the nodes
+// not present in it are printed inline and the nodes are added to it.
+func (n *node) write(b *strings.Builder, printed map[*node]bool) {
+	writeComments := func(n *node) {
+		if printed == nil || printed[n] {
+			return
+		}
+		printed[n] = true
+		for _, c := range n.comments {
+			b.WriteString(inlineComment(c) + " ")
+		}
+	}
+	writeOperand := func(i int) {
+		if n.needsParens(i) {
+			b.WriteString("(")
+			n.children[i].write(b, printed)
+			b.WriteString(")")
+			return
+		}
+		n.children[i].write(b, printed)
+	}
+
+	switch {
+	case n.token == kNOT:
+		writeComments(n)
+		b.WriteString(kNOT + " ")
+		writeOperand(0)
+	case n.isBinary():
+		writeOperand(0)
+		b.WriteString(" ")
+		writeComments(n)
+		b.WriteString(n.token + " ")
+		writeOperand(1)
+	default:
+		writeComments(n)
+		name, _, ok := parseCall(n.token)
+		if !ok {
+			b.WriteString(n.token)
+			return
+		}
+		b.WriteString(name + "(")
+		for i := range n.children {
+			if i > 0 {
+				b.WriteString(", ")
+			}
+			n.children[i].write(b, printed)
+		}
+		b.WriteString(")")
+	}
+}
+
+// hasComments reports whether n or any of its descendants has comments not yet printed.
+func (n *node) hasComments(printed map[*node]bool) bool {
+	if len(n.comments) > 0 && !printed[n] {
+		return true
+	}
+	for _, c := range n.children {
+		if c.hasComments(printed) {
+			return true
+		}
+	}
+	return false
+}
+
+// inlineComment converts a comment so that it can be followed by code on the same line.
+// A "*/" in a line comment is split, so it does not end the block comment early.
+func inlineComment(c string) string {
+	if text, ok := strings.CutPrefix(c, "//"); ok {
+		text = strings.ReplaceAll(strings.TrimSpace(text), "*/", "* /")
+		return "/* " + text + " */"
+	}
+	return c
+}
@@ -2,6 +2,7 @@ package rules
 
 import (
 	"fmt"
+	"reflect"
 )
 
 // Variable represents a value that can be used in a rule.
@@ -9,6 +10,7 @@ type Variable interface {
 	RuleElement
 
 	getValue() any
+	withValue(value any) (Variable, bool)
 
 	equalTo(Variable) Attribute
 	notEqualTo(Variable) Attribute
@@ -68,10 +70,19 @@ func (v variable[T]) getValue() any {
 	return v.value
 }
 
+// withValue returns a copy of v holding value converted to T, if value is of the same kind.
+func (v variable[T]) withValue(value any) (Variable, bool) {
+	target := reflect.TypeOf(v.value)
+	rv := reflect.ValueOf(value)
+	if !rv.IsValid() || kindOf(rv.Type()) != kindOf(target) || !rv.CanConvert(target) {
+		return nil, false
+	}
+	v.value = rv.Convert(target).Interface().(T)
+	return v, true
+}
+
 func (v variable[T]) equalTo(v2 Variable) Attribute {
-	a1 := v.getValue().(T)
-	a2 := v2.getValue().(T)
-	if v.eq(a1, a2) {
+	if v.isEqual(v2) {
 		return attribute{name: "(" + v.name + " == " + v2.getName() + ")", value: true}
 	}
 	return attribute{name: "(" + v.name + " != " + v2.getName() + ")", value: false}
@@ -82,9 +93,7 @@ func (v variable[T]) notEqualTo(v2 Variable) Attribute {
 }
 
 func (v variable[T]) greaterThan(v2 Variable) Attribute {
-	a1 := v.getValue().(T)
-	a2 := v2.getValue().(T)
-	if v.gt(a1, a2) {
+	if v.isGreater(v2) {
 		name := "(" + v.name + " > " + v2.getName() + ")"
 		return attribute{name: name, value: true}
 	}
@@ -93,7 +102,7 @@ func (v variable[T]) greaterThan(v2 Variable) Attribute {
 }
 
 func (v variable[T]) lessThan(v2 Variable) Attribute {
-	return v.greaterThan(v2).not()
+	return v.greaterThanOrEqualTo(v2).not()
 }
 
 func (v variable[T]) greaterThanOrEqualTo(v2 Variable) Attribute {
@@ -101,5 +110,23 @@ func (v variable[T]) greaterThanOrEqualTo(v2 Variable) Attribute {
 }
 
 func (v variable[T]) lessThanOrEqualTo(v2 Variable) Attribute {
-	return v.lessThan(v2).or(v.equalTo(v2))
+	return v.greaterThan(v2).not()
+}
+
+// isEqual compares v with v2 using eq when both hold the same type,
+// and by value otherwise, so that a float64 variable can be compared with a literal.
+func (v variable[T]) isEqual(v2 Variable) bool {
+	if a2, ok := v2.getValue().(T); ok {
+		return v.eq(v.value, a2)
+	}
+	c, _ := compareValues(v.value, v2.getValue())
+	return c == 0
+}
+
+func (v variable[T]) isGreater(v2 Variable) bool {
+	if a2, ok := v2.getValue().(T); ok {
+		return v.gt(v.value, a2)
+	}
+	c, _ := compareValues(v.value, v2.getValue())
+	return c > 0
 }
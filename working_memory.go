@@ -0,0 +1,167 @@
+package rules
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// WorkingMemory is a rule context that can be modified, used by an Engine to hold
+// the facts its actions assert. Each change is stamped, so that the engine knows
+// which rules it may affect.
+type WorkingMemory struct {
+	elems   []RuleElement
+	index   map[string]int
+	stamps  map[string]int
+	version int
+}
+
+// NewWorkingMemory creates a working memory holding the given elements.
+func NewWorkingMemory(elems ...RuleElement) *WorkingMemory {
+	wm := &WorkingMemory{
+		index:  make(map[string]int),
+		stamps: make(map[string]int),
+	}
+	for _, elem := range elems {
+		wm.Set(elem)
+	}
+	return wm
+}
+
+// Set adds elem to the working memory, replacing the element with the same name.
+func (w *WorkingMemory) Set(elem RuleElement) {
+	name := elem.getName()
+	i, ok := w.index[name]
+	if !ok {
+		w.index[name] = len(w.elems)
+		w.elems = append(w.elems, elem)
+		w.touch(name)
+		return
+	}
+
+	if !sameValue(w.elems[i], elem) {
+		w.elems[i] = elem
+		w.touch(name)
+	}
+}
+
+// SetValue sets the value of the element with the given name. An existing variable
+// keeps its type, so the value must be of the same kind. New elements become
+// attributes for booleans and variables otherwise.
+func (w *WorkingMemory) SetValue(name string, value any) error {
+	if i, ok := w.index[name]; ok {
+		switch el := w.elems[i].(type) {
+		case Attribute:
+			b, ok := value.(bool)
+			if !ok {
+				return fmt.Errorf("%w: cannot assign %T to attribute %s", ErrInvalidRule, value, name)
+			}
+			w.Set(attribute{name: name, value: b})
+			return nil
+		case Variable:
+			v, ok := el.withValue(value)
+			if !ok {
+				return fmt.Errorf("%w: cannot assign %T to variable %s holding %T", ErrInvalidRule, value, name, el.getValue())
+			}
+			w.Set(v)
+			return nil
+		}
+	}
+
+	elem, err := newElement(name, value)
+	if err != nil {
+		return err
+	}
+	w.Set(elem)
+	return nil
+}
+
+func (w *WorkingMemory) touch(name string) {
+	w.version++
+	w.stamps[name] = w.version
+}
+
+// changedSince reports whether any of the named elements changed after version.
+// Names not held in the working memory are treated as changed whenever anything changed.
+func (w *WorkingMemory) changedSince(version int, names []string) bool {
+	for _, name := range names {
+		stamp, ok := w.stamps[name]
+		if !ok {
+			stamp = w.version
+		}
+		if i, ok := w.index[name]; ok {
+			if _, computed := w.elems[i].(computedElement); computed {
+				stamp = w.version
+			}
+		}
+		if stamp > version {
+			return true
+		}
+	}
+	return false
+}
+
+func (w *WorkingMemory) String() string {
+	s := make([]string, len(w.elems))
+	for i, elem := range w.elems {
+		s[i] = fmt.Sprint(elem)
+	}
+	return strings.Join(s, ", ")
+}
+
+// MergeWith combines the working memory with ctx into a new context. If an element
+// with the same name exists in both, the element from the working memory is used.
+func (w *WorkingMemory) MergeWith(ctx RuleContext) RuleContext {
+	return NewContext(w.elems...).MergeWith(ctx)
+}
+
+func (w *WorkingMemory) findElement(name string) (RuleElement, bool) {
+	i, ok := w.index[name]
+	if !ok {
+		return nil, false
+	}
+	return w.elems[i], true
+}
+
+func (w *WorkingMemory) listElements() []RuleElement {
+	return w.elems
+}
+
+// newElement creates an attribute or a variable holding value.
+func newElement(name string, value any) (RuleElement, error) {
+	rv := reflect.ValueOf(value)
+	switch {
+	case !rv.IsValid():
+		return nil, fmt.Errorf("%w: no value for %s", ErrInvalidRule, name)
+	case rv.Kind() == reflect.Bool:
+		return attribute{name: name, value: rv.Bool()}, nil
+	case rv.Kind() == reflect.String:
+		return NewVariable[string](name)(rv.String()), nil
+	case isInt(rv.Kind()):
+		return NewVariable[int64](name)(rv.Int()), nil
+	case isUint(rv.Kind()):
+		return NewVariable[uint64](name)(rv.Uint()), nil
+	case isNumber(rv.Kind()):
+		return NewVariable[float64](name)(rv.Float()), nil
+	default:
+		return nil, fmt.Errorf("%w: unsupported value %T for %s", ErrInvalidRule, value, name)
+	}
+}
+
+// sameValue reports whether two elements hold the same value of the same type.
+func sameValue(a, b RuleElement) bool {
+	switch x := a.(type) {
+	case Attribute:
+		y, ok := b.(Attribute)
+		return ok && x.getValue() == y.getValue()
+	case Variable:
+		y, ok := b.(Variable)
+		if !ok || reflect.TypeOf(x.getValue()) != reflect.TypeOf(y.getValue()) {
+			return false
+		}
+		c, ok := compareValues(x.getValue(), y.getValue())
+		return ok && c == 0
+	default:
+		return false
+	}
+}